@@ -0,0 +1,61 @@
+// cache-warmer is a periodic refresher invoked by a scheduled EventBridge
+// rule. It re-acquires the cache warming lock and rescans PostgreSQL so
+// cache staleness stays bounded even when writes happen outside the CSV
+// upload path. The schedule interval is configured on the EventBridge rule,
+// not in this binary.
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/google/uuid"
+
+	"gitlab.connectwisedev.com/product-service/pkg/cache"
+	"gitlab.connectwisedev.com/product-service/pkg/config"
+	"gitlab.connectwisedev.com/product-service/pkg/database"
+	"gitlab.connectwisedev.com/product-service/pkg/warmer"
+)
+
+var (
+	dbClient    *database.DBClient
+	cacheStore  cache.Store
+	cacheWarmer *warmer.Warmer
+)
+
+func init() {
+	config.LoadEnv() // Load environment variables first
+
+	var err error
+	dbClient, err = database.NewPostgresClient()
+	if err != nil {
+		log.Fatalf("Failed to initialize DB client: %v", err)
+	}
+
+	cacheStore, err = cache.NewStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize cache store: %v", err)
+	}
+
+	cacheWarmer = warmer.New(dbClient, cacheStore, uuid.New().String())
+}
+
+// handler refreshes the product cache on each scheduled invocation. The
+// EventBridge scheduled event payload carries no data we need, so it's
+// accepted and ignored.
+func handler(ctx context.Context, event map[string]interface{}) error {
+	count, err := cacheWarmer.Refresh(ctx)
+	if err != nil {
+		log.Printf("Cache warming refresh failed: %v", err)
+		return err
+	}
+	log.Printf("Cache warming refresh complete: %d products.", count)
+	return nil
+}
+
+func main() {
+	defer dbClient.Close()
+	defer cacheStore.Close()
+	lambda.Start(handler)
+}
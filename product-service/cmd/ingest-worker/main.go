@@ -0,0 +1,194 @@
+// ingest-worker consumes rows enqueued by the upload-csv Lambda, applying
+// each row's UPSERT and cache update in its own short transaction. Rows
+// that keep failing are moved to the dead-letter queue after
+// INGEST_MAX_ATTEMPTS attempts, with the original row, the error, and the
+// attempt count recorded. A pkg/limiter instance keeps one noisy source
+// from starving DB/Redis capacity for the rest: rows over their source's
+// rate or in-flight cap are deferred (re-enqueued with a delay) rather than
+// processed. It runs as a scheduled Lambda that drains one batch per
+// invocation; it has no long-running consumer loop.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"gitlab.connectwisedev.com/product-service/pkg/cache"
+	"gitlab.connectwisedev.com/product-service/pkg/config"
+	"gitlab.connectwisedev.com/product-service/pkg/database"
+	"gitlab.connectwisedev.com/product-service/pkg/ingest"
+	"gitlab.connectwisedev.com/product-service/pkg/limiter"
+	"gitlab.connectwisedev.com/product-service/pkg/queue"
+)
+
+// deferDelay is how long a rate- or concurrency-limited job waits before
+// it's eligible for redelivery.
+const deferDelay = 15 * time.Second
+
+var (
+	dbClient   *database.DBClient
+	cacheStore cache.Store
+	consumer   queue.Consumer
+	rateLimit  *limiter.Limiter
+	cfg        queue.Config
+)
+
+func init() {
+	config.LoadEnv() // Load environment variables first
+
+	var err error
+	dbClient, err = database.NewPostgresClient()
+	if err != nil {
+		log.Fatalf("Failed to initialize DB client: %v", err)
+	}
+
+	cacheStore, err = cache.NewStoreFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to initialize cache store: %v", err)
+	}
+
+	cfg, err = queue.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load queue config: %v", err)
+	}
+
+	consumer, err = queue.NewConsumer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize ingest queue consumer: %v", err)
+	}
+
+	limiterCfg, err := limiter.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load limiter config: %v", err)
+	}
+	rateLimit, err = limiter.New(limiterCfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize ingest rate limiter: %v", err)
+	}
+}
+
+// handler drains one batch of ingest jobs per invocation, processing up to
+// cfg.MaxInFlight rows concurrently.
+func handler(ctx context.Context, event map[string]interface{}) error {
+	jobs, err := consumer.Read(ctx, cfg.BatchSize, cfg.VisibilityTimeout)
+	if err != nil {
+		log.Printf("Failed to read jobs from ingest queue: %v", err)
+		return err
+	}
+
+	if len(jobs) == 0 {
+		log.Println("No ingest jobs available.")
+		return nil
+	}
+
+	// A zero or negative MaxInFlight means "no cap" (consistent with the
+	// limiter package's treatment of its own zero-value in-flight/rate
+	// settings); size the channel to the batch so sends never block.
+	maxInFlight := cfg.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = len(jobs)
+	}
+	sem := make(chan struct{}, maxInFlight)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		job := job
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			processJob(ctx, job)
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("Processed %d ingest jobs.", len(jobs))
+	return nil
+}
+
+// processJob applies a single row's UPSERT, acknowledging it on success,
+// requeuing it for another attempt on failure, or dead-lettering it once
+// INGEST_MAX_ATTEMPTS is exhausted.
+func processJob(ctx context.Context, job queue.Job) {
+	var ingestJob ingest.Job
+	if err := json.Unmarshal(job.Payload, &ingestJob); err != nil {
+		log.Printf("Dropping unparseable ingest job %s: %v", job.ID, err)
+		if err := consumer.DeadLetter(ctx, job, err, 0); err != nil {
+			log.Printf("Failed to dead-letter unparseable job %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	source := limiter.Source(ingestJob.Row.TenantID, ingestJob.Source)
+
+	allowed, err := rateLimit.AllowRow(ctx, source)
+	if err != nil {
+		log.Printf("Rate limit check failed for source %s (job %s), processing anyway: %v", source, job.ID, err)
+	} else if !allowed {
+		log.Printf("Source %s over its row rate limit, deferring job %s", source, job.ID)
+		if deferErr := consumer.Defer(ctx, job, job.Payload, deferDelay); deferErr != nil {
+			log.Printf("Failed to defer rate-limited job %s: %v", job.ID, deferErr)
+		}
+		return
+	}
+
+	acquired, err := rateLimit.AcquireSlot(ctx, source)
+	if err != nil {
+		log.Printf("In-flight slot check failed for source %s (job %s), processing anyway: %v", source, job.ID, err)
+	} else if !acquired {
+		log.Printf("Source %s at its in-flight cap, deferring job %s", source, job.ID)
+		if deferErr := consumer.Defer(ctx, job, job.Payload, deferDelay); deferErr != nil {
+			log.Printf("Failed to defer in-flight-capped job %s: %v", job.ID, deferErr)
+		}
+		return
+	}
+	if acquired {
+		defer func() {
+			if err := rateLimit.ReleaseSlot(ctx, source); err != nil {
+				log.Printf("Failed to release in-flight slot for source %s: %v", source, err)
+			}
+		}()
+	}
+
+	rowCtx, cancel := context.WithTimeout(ctx, cfg.VisibilityTimeout)
+	defer cancel()
+
+	_, err = ingest.UpsertRow(rowCtx, dbClient, cacheStore, ingestJob.Row)
+	if err == nil {
+		if ackErr := consumer.Ack(ctx, job); ackErr != nil {
+			log.Printf("Failed to ack job %s: %v", job.ID, ackErr)
+		}
+		return
+	}
+
+	log.Printf("Attempt %d failed for product %q (job %s): %v", ingestJob.Attempt, ingestJob.Row.Name, job.ID, err)
+
+	if ingestJob.Attempt >= cfg.MaxAttempts {
+		if dlqErr := consumer.DeadLetter(ctx, job, err, ingestJob.Attempt); dlqErr != nil {
+			log.Printf("Failed to dead-letter job %s: %v", job.ID, dlqErr)
+		}
+		return
+	}
+
+	retryPayload, marshalErr := json.Marshal(ingest.Job{Row: ingestJob.Row, Attempt: ingestJob.Attempt + 1, Source: ingestJob.Source})
+	if marshalErr != nil {
+		log.Printf("Failed to marshal retry payload for job %s: %v", job.ID, marshalErr)
+		return
+	}
+	if retryErr := consumer.Retry(ctx, job, retryPayload); retryErr != nil {
+		log.Printf("Failed to requeue job %s for retry: %v", job.ID, retryErr)
+	}
+}
+
+func main() {
+	defer dbClient.Close()
+	defer cacheStore.Close()
+	defer consumer.Close()
+	defer rateLimit.Close()
+	lambda.Start(handler)
+}
@@ -7,21 +7,22 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
 
 	"gitlab.connectwisedev.com/product-service/models"
 	"gitlab.connectwisedev.com/product-service/pkg/cache"
 	"gitlab.connectwisedev.com/product-service/pkg/config"
 	"gitlab.connectwisedev.com/product-service/pkg/database"
+	"gitlab.connectwisedev.com/product-service/pkg/warmer"
 )
 
 var (
 	dbClient    *database.DBClient
-	redisClient *cache.RedisClient
+	cacheStore  cache.Store
+	cacheWarmer *warmer.Warmer
 	ctx         = context.Background()
 )
 
@@ -34,10 +35,12 @@ func init() {
 		log.Fatalf("Failed to initialize DB client: %v", err)
 	}
 
-	redisClient, err = cache.NewRedisClient()
+	cacheStore, err = cache.NewStoreFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to initialize Redis client: %v", err)
+		log.Fatalf("Failed to initialize cache store: %v", err)
 	}
+
+	cacheWarmer = warmer.New(dbClient, cacheStore, uuid.New().String())
 }
 
 func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
@@ -45,7 +48,7 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 	products, err := getProductsFromCache()
 	if err != nil {
-		log.Printf("Error fetching from Redis (%v), falling back to DB.", err)
+		log.Printf("Error fetching from cache (%v), falling back to DB.", err)
 		products, err = getProductsFromDB()
 		if err != nil {
 			log.Printf("Error fetching from DB: %v", err)
@@ -55,11 +58,13 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 				Body:       `{"message": "Failed to retrieve products"}`,
 			}, nil
 		}
-		// If fetched from DB, try to populate cache for next time.
-		// Run as a goroutine to not block the main request path.
+		// Try to repopulate the cache for next time, guarded by a
+		// Redis-based lock so concurrent invocations don't all rescan the
+		// DB. Run as a goroutine to not block the main request path; if
+		// another invocation already holds the lock, Refresh is a no-op
+		// and this invocation simply serves the DB result fetched above.
 		go func() {
-			err := populateCache(products)
-			if err != nil {
+			if _, err := cacheWarmer.Refresh(ctx); err != nil {
 				log.Printf("Failed to populate cache after DB fetch: %v", err)
 			}
 		}()
@@ -96,47 +101,31 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 }
 
 func getProductsFromCache() ([]models.Product, error) {
-	// Get all product IDs from the Redis set
-	productIDs, err := redisClient.GetClient().SMembers(ctx, "all_product_ids").Result()
+	// Get all product IDs tracked by the cache
+	productIDs, err := cacheStore.ListProductIDs(ctx)
 	if err != nil {
-		if err == redis.Nil { // Set does not exist
-			return nil, fmt.Errorf("Redis set 'all_product_ids' does not exist or is empty")
-		}
-		return nil, fmt.Errorf("failed to get all_product_ids from Redis: %w", err)
+		return nil, fmt.Errorf("failed to list product IDs from cache: %w", err)
 	}
 	if len(productIDs) == 0 {
-		return nil, fmt.Errorf("no product IDs found in Redis cache set")
+		return nil, fmt.Errorf("no product IDs found in cache")
 	}
 
-	// Create keys for MGET
-	keys := make([]string, len(productIDs))
-	for i, id := range productIDs {
-		keys[i] = fmt.Sprintf("product:%s", id)
-	}
-
-	// Fetch all product JSONs using MGET for efficiency
-	results, err := redisClient.GetClient().MGet(ctx, keys...).Result()
+	productJSONs, err := cacheStore.GetProducts(ctx, productIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to MGET products from Redis: %w", err)
+		return nil, fmt.Errorf("failed to get products from cache: %w", err)
 	}
 
 	var products []models.Product
-	for _, res := range results {
-		if res == nil {
-			// This can happen if a key expired or was evicted from Redis.
+	for i, productJSON := range productJSONs {
+		if productJSON == nil {
+			// This can happen if a key expired or was evicted.
 			// Log and continue, as the DB fallback will cover it.
-			log.Println("Found nil result for a product key in Redis, likely evicted/expired. Will re-fetch from DB if full cache miss.")
-			continue
-		}
-		productJSON, ok := res.(string)
-		if !ok {
-			log.Printf("Unexpected type from Redis MGET: %T", res)
+			log.Printf("Cache miss for product %s, likely evicted/expired. Will re-fetch from DB if full cache miss.", productIDs[i])
 			continue
 		}
 		var product models.Product
-		err := json.Unmarshal([]byte(productJSON), &product)
-		if err != nil {
-			log.Printf("Failed to unmarshal product JSON from Redis: %v", err)
+		if err := json.Unmarshal(productJSON, &product); err != nil {
+			log.Printf("Failed to unmarshal product JSON from cache: %v", err)
 			continue
 		}
 		products = append(products, product)
@@ -147,7 +136,7 @@ func getProductsFromCache() ([]models.Product, error) {
 		return nil, fmt.Errorf("all products from cache were invalid or missing after retrieval, forcing DB fetch")
 	}
 
-	log.Printf("Successfully retrieved %d products from Redis cache.", len(products))
+	log.Printf("Successfully retrieved %d products from cache.", len(products))
 	return products, nil
 }
 
@@ -180,41 +169,8 @@ func getProductsFromDB() ([]models.Product, error) {
 	return products, nil
 }
 
-// populateCache clears and then re-populates the entire 'all_product_ids' set and individual product keys
-func populateCache(products []models.Product) error {
-	pipe := redisClient.GetClient().Pipeline()
-	allProductIDs := make([]interface{}, len(products)) // To store IDs for SADD
-
-	// Add/Update individual product entries and collect their IDs
-	for i, p := range products {
-		productJSON, err := json.Marshal(p)
-		if err != nil {
-			log.Printf("Failed to marshal product %s for cache population: %v", p.ID, err)
-			continue
-		}
-		// Set a TTL (e.g., 5 minutes) for individual product keys.
-		// This helps with eventual consistency if a product is deleted/changed by other means.
-		pipe.Set(ctx, fmt.Sprintf("product:%s", p.ID), productJSON, 5*time.Minute)
-		allProductIDs[i] = p.ID
-	}
-
-	// Clear existing product IDs set and add new ones to ensure consistency.
-	// This is the most straightforward way to ensure 'all_product_ids' accurately reflects the DB.
-	pipe.Del(ctx, "all_product_ids") // Remove old set of product IDs
-	if len(allProductIDs) > 0 {
-		pipe.SAdd(ctx, "all_product_ids", allProductIDs...) // Add all current product IDs
-	}
-
-	_, err := pipe.Exec(ctx) // Execute all pipeline commands
-	if err != nil {
-		return fmt.Errorf("failed to execute Redis pipeline for cache population: %w", err)
-	}
-	log.Printf("Cache populated with %d products.", len(products))
-	return nil
-}
-
 func main() {
 	defer dbClient.Close()
-	defer redisClient.Close()
+	defer cacheStore.Close()
 	lambda.Start(handler)
 }
@@ -15,13 +15,3 @@ type Product struct {
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
-
-// ProductCSV represents a product as read from a CSV file
-type ProductCSV struct {
-	ID    string  `csv:"id"` // Optional: if CSV has ID, else generate
-	Name  string  `csv:"name"`
-	Image string  `csv:"image"`
-	Price float64 `csv:"price"`
-	Qty   int     `csv:"qty"`
-	// out_of_stock is derived from qty, not directly from CSV
-}
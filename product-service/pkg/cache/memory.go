@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store implementation backed by a map. It has
+// no external dependencies, which makes it useful for local development and
+// unit tests that shouldn't require a live Redis.
+type MemoryStore struct {
+	mu       sync.Mutex
+	products map[string][]byte
+	ids      map[string]struct{}
+	locks    map[string]memoryLock
+}
+
+type memoryLock struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		products: make(map[string][]byte),
+		ids:      make(map[string]struct{}),
+		locks:    make(map[string]memoryLock),
+	}
+}
+
+// AcquireLock takes an in-process lock, honoring ttl the same way the Redis
+// backend would (a stale, unreleased lock is reclaimable once expired).
+func (s *MemoryStore) AcquireLock(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.locks[key]; ok && time.Now().Before(existing.expiresAt) {
+		return false, nil
+	}
+	s.locks[key] = memoryLock{value: value, expiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+// ReleaseLock releases a lock only if it is still held with the same value.
+func (s *MemoryStore) ReleaseLock(ctx context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.locks[key]; ok && existing.value == value {
+		delete(s.locks, key)
+	}
+	return nil
+}
+
+// GetProduct returns the cached JSON for a product, or ErrNotFound if it
+// isn't cached. The stored TTL passed to SetProduct is not enforced; entries
+// live until explicitly removed.
+func (s *MemoryStore) GetProduct(ctx context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	productJSON, ok := s.products[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return productJSON, nil
+}
+
+// GetProducts returns the cached JSON for each of ids, in the same order as
+// ids. The entry for an id that isn't cached is nil.
+func (s *MemoryStore) GetProducts(ctx context.Context, ids []string) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	productJSONs := make([][]byte, len(ids))
+	for i, id := range ids {
+		productJSONs[i] = s.products[id]
+	}
+	return productJSONs, nil
+}
+
+// SetProduct caches a product's JSON. The ttl parameter is accepted for
+// interface compatibility with RedisStore but is not enforced.
+func (s *MemoryStore) SetProduct(ctx context.Context, id string, productJSON []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products[id] = productJSON
+	return nil
+}
+
+// ListProductIDs returns every tracked product ID.
+func (s *MemoryStore) ListProductIDs(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.ids))
+	for id := range s.ids {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// AddProductID tracks a product ID.
+func (s *MemoryStore) AddProductID(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ids[id] = struct{}{}
+	return nil
+}
+
+// RemoveProductID stops tracking a product ID.
+func (s *MemoryStore) RemoveProductID(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.ids, id)
+	return nil
+}
+
+// Pipeline returns a batched writer that applies its queued operations
+// directly to the store on Exec.
+func (s *MemoryStore) Pipeline() Pipeline {
+	return &memoryPipeline{store: s}
+}
+
+// Close is a no-op; MemoryStore holds no external resources.
+func (s *MemoryStore) Close() {}
+
+type memoryOp struct {
+	id         string
+	productSet bool
+	productVal []byte
+	idAdd      bool
+	idRemove   bool
+	idsClear   bool
+}
+
+// memoryPipeline implements Pipeline by queuing operations and replaying
+// them against the backing MemoryStore on Exec.
+type memoryPipeline struct {
+	store *MemoryStore
+	ops   []memoryOp
+}
+
+func (p *memoryPipeline) SetProduct(id string, productJSON []byte, ttl time.Duration) {
+	p.ops = append(p.ops, memoryOp{id: id, productSet: true, productVal: productJSON})
+}
+
+func (p *memoryPipeline) AddProductID(id string) {
+	p.ops = append(p.ops, memoryOp{id: id, idAdd: true})
+}
+
+func (p *memoryPipeline) RemoveProductID(id string) {
+	p.ops = append(p.ops, memoryOp{id: id, idRemove: true})
+}
+
+func (p *memoryPipeline) ClearProductIDs() {
+	p.ops = append(p.ops, memoryOp{idsClear: true})
+}
+
+func (p *memoryPipeline) Exec(ctx context.Context) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+
+	for _, op := range p.ops {
+		if op.idsClear {
+			p.store.ids = make(map[string]struct{})
+		}
+		if op.productSet {
+			p.store.products[op.id] = op.productVal
+		}
+		if op.idAdd {
+			p.store.ids[op.id] = struct{}{}
+		}
+		if op.idRemove {
+			delete(p.store.ids, op.id)
+		}
+	}
+	p.ops = nil
+	return nil
+}
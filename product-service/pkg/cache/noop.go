@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// NoopStore discards every write and never reports a cache hit. It exists
+// so the cache layer can be disabled entirely (e.g. CACHE_BACKEND=noop)
+// while leaving handler code unchanged; every read falls through to the
+// database.
+type NoopStore struct{}
+
+// NewNoopStore returns a Store that never caches anything.
+func NewNoopStore() *NoopStore {
+	return &NoopStore{}
+}
+
+func (s *NoopStore) GetProduct(ctx context.Context, id string) ([]byte, error) {
+	return nil, ErrNotFound
+}
+
+func (s *NoopStore) GetProducts(ctx context.Context, ids []string) ([][]byte, error) {
+	return make([][]byte, len(ids)), nil
+}
+
+func (s *NoopStore) SetProduct(ctx context.Context, id string, productJSON []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (s *NoopStore) ListProductIDs(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (s *NoopStore) AddProductID(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s *NoopStore) RemoveProductID(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s *NoopStore) Pipeline() Pipeline {
+	return &noopPipeline{}
+}
+
+// AcquireLock always succeeds: with no cache to coordinate, there's nothing
+// to protect against concurrent population.
+func (s *NoopStore) AcquireLock(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *NoopStore) ReleaseLock(ctx context.Context, key, value string) error {
+	return nil
+}
+
+func (s *NoopStore) Close() {}
+
+type noopPipeline struct{}
+
+func (p *noopPipeline) SetProduct(id string, productJSON []byte, ttl time.Duration) {}
+
+func (p *noopPipeline) AddProductID(id string) {}
+
+func (p *noopPipeline) RemoveProductID(id string) {}
+
+func (p *noopPipeline) ClearProductIDs() {}
+
+func (p *noopPipeline) Exec(ctx context.Context) error { return nil }
@@ -2,53 +2,404 @@ package cache
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 )
 
+// Mode selects which Redis deployment topology to connect to.
+type Mode string
+
+const (
+	// ModeStandalone talks to a single Redis server.
+	ModeStandalone Mode = "standalone"
+	// ModeSentinel talks to a Sentinel-managed master/replica set.
+	ModeSentinel Mode = "sentinel"
+	// ModeCluster talks to a Redis Cluster.
+	ModeCluster Mode = "cluster"
+)
+
+// Cmdable is the subset of redis command behavior relied on by RedisStore,
+// which backs the product cache for the get-products Lambda, the
+// cache-warmer, and the ingest-worker. *redis.Client, *redis.FailoverClient
+// (Sentinel), and *redis.ClusterClient all satisfy it, so handler code never
+// needs to know which topology is backing the cache.
+type Cmdable interface {
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.BoolCmd
+	SAdd(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	SRem(ctx context.Context, key string, members ...interface{}) *redis.IntCmd
+	MGet(ctx context.Context, keys ...string) *redis.SliceCmd
+	SMembers(ctx context.Context, key string) *redis.StringSliceCmd
+	Pipeline() redis.Pipeliner
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) *redis.Cmd
+	Ping(ctx context.Context) *redis.StatusCmd
+}
+
+// Config holds the settings needed to establish a Redis connection in any
+// of the supported topologies.
+type Config struct {
+	Mode       Mode
+	Addrs      []string
+	MasterName string
+	Password   string
+	DB         int
+	TLS        bool
+	URI        string
+}
+
 // RedisClient holds the Redis client connection
 type RedisClient struct {
-	client *redis.Client
+	client Cmdable
+}
+
+// ConfigFromEnv builds a Config from REDIS_MODE, REDIS_ADDRS/REDIS_ADDR,
+// REDIS_MASTER_NAME, REDIS_PASSWORD, REDIS_DB, REDIS_TLS, and REDIS_URI.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Mode:       Mode(strings.ToLower(os.Getenv("REDIS_MODE"))),
+		MasterName: os.Getenv("REDIS_MASTER_NAME"),
+		Password:   os.Getenv("REDIS_PASSWORD"),
+		URI:        os.Getenv("REDIS_URI"),
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ModeStandalone
+	}
+
+	if addrs := os.Getenv("REDIS_ADDRS"); addrs != "" {
+		cfg.Addrs = strings.Split(addrs, ",")
+	} else if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		cfg.Addrs = []string{addr}
+	}
+
+	if dbStr := os.Getenv("REDIS_DB"); dbStr != "" {
+		db, err := strconv.Atoi(dbStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_DB value %q: %w", dbStr, err)
+		}
+		cfg.DB = db
+	}
+
+	if tlsStr := os.Getenv("REDIS_TLS"); tlsStr != "" {
+		tlsEnabled, err := strconv.ParseBool(tlsStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REDIS_TLS value %q: %w", tlsStr, err)
+		}
+		cfg.TLS = tlsEnabled
+	}
+
+	if cfg.URI == "" && len(cfg.Addrs) == 0 {
+		return Config{}, fmt.Errorf("no Redis connection info set: provide REDIS_URI, REDIS_ADDRS, or REDIS_ADDR")
+	}
+
+	return cfg, nil
 }
 
-// NewRedisClient initializes and returns a new Redis client
+// NewRedisClient initializes and returns a new Redis client using settings
+// read from the environment. It is kept as a thin wrapper around
+// NewRedisClientFromConfig for backward compatibility with existing callers.
 func NewRedisClient() (*RedisClient, error) {
-	addr := os.Getenv("REDIS_ADDR")
-	if addr == "" {
-		return nil, fmt.Errorf("REDIS_ADDR environment variable not set")
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
 	}
+	return NewRedisClientFromConfig(cfg)
+}
 
-	client := redis.NewClient(&redis.Options{
-		Addr:     addr,
-		Password: "", // No password by default for local Redis
-		DB:       0,  // Default DB
-	})
+// NewRedisClientFromConfig initializes a Redis client against a standalone
+// server, a Sentinel-managed master, or a Redis Cluster, depending on
+// cfg.Mode. In standalone mode cfg.URI, if set, takes precedence over
+// cfg.Addrs and is parsed with redis.ParseURL, which only understands
+// standalone connection strings. Sentinel mode returns an error if cfg.URI is
+// set and requires cfg.Addrs/cfg.MasterName; cluster mode ignores cfg.URI and
+// requires cfg.Addrs (see NewUniversalClientFromConfig).
+func NewRedisClientFromConfig(cfg Config) (*RedisClient, error) {
+	client, err := NewUniversalClientFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisClient{client: client}, nil
+}
+
+// NewUniversalClientFromConfig builds the go-redis client appropriate for
+// cfg.Mode and verifies connectivity with a Ping. It returns
+// redis.UniversalClient (the full command set), for callers such as
+// pkg/queue that need commands, like the stream family, beyond the narrow
+// Cmdable subset used by the product cache.
+func NewUniversalClientFromConfig(cfg Config) (redis.UniversalClient, error) {
+	var client redis.UniversalClient
+
+	switch cfg.Mode {
+	case ModeCluster:
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("REDIS_ADDRS must be set for cluster mode")
+		}
+		opts := &redis.ClusterOptions{
+			Addrs:    cfg.Addrs,
+			Password: cfg.Password,
+		}
+		if cfg.TLS {
+			opts.TLSConfig = &tls.Config{}
+		}
+		client = redis.NewClusterClient(opts)
+
+	case ModeSentinel:
+		// redis.ParseURL only understands standalone connection strings
+		// (redis://, rediss://, unix://) — there is no sentinel scheme, so
+		// handing a sentinel node's URI to it would silently produce a
+		// standalone *redis.Client pointed at that node instead of a
+		// FailoverClient. Sentinel's Ping still succeeds against that node,
+		// so the misconfiguration wouldn't surface until the first real
+		// command failed. Require REDIS_ADDRS/REDIS_MASTER_NAME instead.
+		if cfg.URI != "" {
+			return nil, fmt.Errorf("REDIS_URI is not supported for sentinel mode: set REDIS_ADDRS and REDIS_MASTER_NAME instead")
+		}
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("REDIS_ADDRS must be set for sentinel mode")
+		}
+		if cfg.MasterName == "" {
+			return nil, fmt.Errorf("REDIS_MASTER_NAME must be set for sentinel mode")
+		}
+		opts := &redis.FailoverOptions{
+			MasterName:    cfg.MasterName,
+			SentinelAddrs: cfg.Addrs,
+			Password:      cfg.Password,
+			DB:            cfg.DB,
+		}
+		if cfg.TLS {
+			opts.TLSConfig = &tls.Config{}
+		}
+		client = redis.NewFailoverClient(opts)
+
+	case ModeStandalone, "":
+		if cfg.URI != "" {
+			opts, err := redis.ParseURL(cfg.URI)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse REDIS_URI: %w", err)
+			}
+			client = redis.NewClient(opts)
+			break
+		}
+		if len(cfg.Addrs) == 0 {
+			return nil, fmt.Errorf("REDIS_ADDR or REDIS_ADDRS must be set for standalone mode")
+		}
+		opts := &redis.Options{
+			Addr:     cfg.Addrs[0],
+			Password: cfg.Password,
+			DB:       cfg.DB,
+		}
+		if cfg.TLS {
+			opts.TLSConfig = &tls.Config{}
+		}
+		client = redis.NewClient(opts)
+
+	default:
+		return nil, fmt.Errorf("unknown REDIS_MODE %q: must be standalone, sentinel, or cluster", cfg.Mode)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	pong, err := client.Ping(ctx).Result()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+		return nil, fmt.Errorf("failed to connect to Redis (%s mode): %w", cfg.Mode, err)
 	}
-	log.Printf("Successfully connected to Redis! Ping response: %s", pong)
+	log.Printf("Successfully connected to Redis in %s mode! Ping response: %s", cfg.Mode, pong)
 
-	return &RedisClient{client: client}, nil
+	return client, nil
 }
 
 // Close closes the Redis connection
 func (c *RedisClient) Close() {
-	if c.client != nil {
-		c.client.Close()
+	if closer, ok := c.client.(interface{ Close() error }); ok {
+		if err := closer.Close(); err != nil {
+			log.Printf("Error closing Redis connection: %v", err)
+			return
+		}
 		log.Println("Redis connection closed.")
 	}
 }
 
-// GetClient returns the underlying *redis.Client instance
-func (c *RedisClient) GetClient() *redis.Client {
+// GetClient returns the underlying Cmdable instance. The concrete type
+// depends on the configured mode (*redis.Client, *redis.FailoverClient, or
+// *redis.ClusterClient), but all three support the same operations used by
+// the handlers.
+func (c *RedisClient) GetClient() Cmdable {
 	return c.client
 }
+
+const (
+	allProductIDsKey  = "all_product_ids"
+	productKeyPrefix  = "product:"
+	defaultProductTTL = 5 * time.Minute
+)
+
+func productKey(id string) string {
+	return productKeyPrefix + id
+}
+
+// RedisStore is the Store implementation backed by Redis (standalone,
+// Sentinel, or Cluster, depending on how the underlying RedisClient was
+// constructed).
+type RedisStore struct {
+	redisClient *RedisClient
+}
+
+// NewRedisStore wraps an already-connected RedisClient as a Store.
+func NewRedisStore(redisClient *RedisClient) *RedisStore {
+	return &RedisStore{redisClient: redisClient}
+}
+
+// GetProduct returns the cached JSON for a product, or ErrNotFound if it
+// isn't cached.
+func (s *RedisStore) GetProduct(ctx context.Context, id string) ([]byte, error) {
+	val, err := s.redisClient.GetClient().Get(ctx, productKey(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get product %s from Redis: %w", id, err)
+	}
+	return val, nil
+}
+
+// GetProducts returns the cached JSON for each of ids via a single MGET,
+// in the same order as ids. The entry for an id that isn't cached (expired
+// or evicted) is nil.
+func (s *RedisStore) GetProducts(ctx context.Context, ids []string) ([][]byte, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = productKey(id)
+	}
+
+	results, err := s.redisClient.GetClient().MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to MGET products from Redis: %w", err)
+	}
+
+	productJSONs := make([][]byte, len(results))
+	for i, res := range results {
+		if res == nil {
+			continue
+		}
+		str, ok := res.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type from Redis MGET for product %s: %T", ids[i], res)
+		}
+		productJSONs[i] = []byte(str)
+	}
+	return productJSONs, nil
+}
+
+// SetProduct caches a product's JSON with the given TTL (0 means no
+// expiration).
+func (s *RedisStore) SetProduct(ctx context.Context, id string, productJSON []byte, ttl time.Duration) error {
+	if err := s.redisClient.GetClient().Set(ctx, productKey(id), productJSON, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set product %s in Redis: %w", id, err)
+	}
+	return nil
+}
+
+// ListProductIDs returns every product ID tracked in the all_product_ids set.
+func (s *RedisStore) ListProductIDs(ctx context.Context) ([]string, error) {
+	ids, err := s.redisClient.GetClient().SMembers(ctx, allProductIDsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list product IDs from Redis: %w", err)
+	}
+	return ids, nil
+}
+
+// AddProductID adds a product ID to the all_product_ids set.
+func (s *RedisStore) AddProductID(ctx context.Context, id string) error {
+	if err := s.redisClient.GetClient().SAdd(ctx, allProductIDsKey, id).Err(); err != nil {
+		return fmt.Errorf("failed to add product ID %s to Redis: %w", id, err)
+	}
+	return nil
+}
+
+// RemoveProductID removes a product ID from the all_product_ids set.
+func (s *RedisStore) RemoveProductID(ctx context.Context, id string) error {
+	if err := s.redisClient.GetClient().SRem(ctx, allProductIDsKey, id).Err(); err != nil {
+		return fmt.Errorf("failed to remove product ID %s from Redis: %w", id, err)
+	}
+	return nil
+}
+
+// Pipeline returns a batched writer backed by a Redis pipeline.
+func (s *RedisStore) Pipeline() Pipeline {
+	return &redisPipeline{pipe: s.redisClient.GetClient().Pipeline()}
+}
+
+// releaseLockScript deletes key only if its current value still matches the
+// caller's, so a lock holder can never release a lock it no longer owns
+// (e.g. its TTL already expired and another caller acquired it).
+const releaseLockScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+else
+	return 0
+end
+`
+
+// AcquireLock takes a Redis-based mutex using SET key value NX PX ttl.
+func (s *RedisStore) AcquireLock(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	ok, err := s.redisClient.GetClient().SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire Redis lock %s: %w", key, err)
+	}
+	return ok, nil
+}
+
+// ReleaseLock releases a lock previously taken with AcquireLock via a Lua
+// compare-and-delete so it only frees the lock if this caller still holds it.
+func (s *RedisStore) ReleaseLock(ctx context.Context, key, value string) error {
+	if err := s.redisClient.GetClient().Eval(ctx, releaseLockScript, []string{key}, value).Err(); err != nil {
+		return fmt.Errorf("failed to release Redis lock %s: %w", key, err)
+	}
+	return nil
+}
+
+// Close releases the underlying Redis connection.
+func (s *RedisStore) Close() {
+	s.redisClient.Close()
+}
+
+// redisPipeline implements Pipeline on top of redis.Pipeliner.
+type redisPipeline struct {
+	pipe redis.Pipeliner
+}
+
+func (p *redisPipeline) SetProduct(id string, productJSON []byte, ttl time.Duration) {
+	p.pipe.Set(context.Background(), productKey(id), productJSON, ttl)
+}
+
+func (p *redisPipeline) AddProductID(id string) {
+	p.pipe.SAdd(context.Background(), allProductIDsKey, id)
+}
+
+func (p *redisPipeline) RemoveProductID(id string) {
+	p.pipe.SRem(context.Background(), allProductIDsKey, id)
+}
+
+func (p *redisPipeline) ClearProductIDs() {
+	p.pipe.Del(context.Background(), allProductIDsKey)
+}
+
+func (p *redisPipeline) Exec(ctx context.Context) error {
+	_, err := p.pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to execute Redis pipeline: %w", err)
+	}
+	return nil
+}
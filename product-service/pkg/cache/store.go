@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrNotFound is returned by Store.GetProduct when no value is cached for
+// the given product ID.
+var ErrNotFound = errors.New("cache: product not found")
+
+// Backend selects which Store implementation backs the cache.
+type Backend string
+
+const (
+	// BackendRedis stores products in Redis (standalone, Sentinel, or Cluster).
+	BackendRedis Backend = "redis"
+	// BackendMemory stores products in an in-process map. Useful for local
+	// development and unit tests that don't need a live Redis.
+	BackendMemory Backend = "memory"
+	// BackendNoop discards writes and never serves a cache hit, forcing
+	// every read through to the database.
+	BackendNoop Backend = "noop"
+)
+
+// Pipeline batches a set of cache writes so a backend can apply them in one
+// round trip (e.g. a single Redis pipeline) instead of one call per
+// product. On RedisStore the pipeline is not a MULTI/EXEC transaction, so its
+// writes are not applied atomically.
+type Pipeline interface {
+	SetProduct(id string, productJSON []byte, ttl time.Duration)
+	AddProductID(id string)
+	RemoveProductID(id string)
+	// ClearProductIDs queues removal of the entire product ID set, so a
+	// pipeline can rebuild it from scratch (see pkg/warmer).
+	ClearProductIDs()
+	// Exec applies the batched writes. It is a no-op to call Exec on a
+	// pipeline with no queued operations.
+	Exec(ctx context.Context) error
+}
+
+// Store is the storage interface both Lambdas use to read and write the
+// product cache. Redis is the production backend; Memory and Noop exist so
+// the handlers can run without a live Redis.
+type Store interface {
+	// GetProduct returns the cached JSON for a product, or ErrNotFound if
+	// it isn't cached.
+	GetProduct(ctx context.Context, id string) ([]byte, error)
+	// GetProducts returns the cached JSON for each of ids in one round trip
+	// (an MGET on RedisStore), in the same order as ids. The entry for an id
+	// that isn't cached is nil rather than ErrNotFound, since a partial miss
+	// across a batch is the expected case, not an error.
+	GetProducts(ctx context.Context, ids []string) ([][]byte, error)
+	SetProduct(ctx context.Context, id string, productJSON []byte, ttl time.Duration) error
+	// ListProductIDs returns every product ID currently tracked by the cache.
+	ListProductIDs(ctx context.Context) ([]string, error)
+	AddProductID(ctx context.Context, id string) error
+	RemoveProductID(ctx context.Context, id string) error
+	Pipeline() Pipeline
+
+	// AcquireLock attempts to take a mutex identified by key, stamped with
+	// value (an identifier for the caller), expiring after ttl if never
+	// released. It returns false, nil if another caller already holds it.
+	AcquireLock(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// ReleaseLock releases a lock previously taken with AcquireLock, but
+	// only if it is still held with the same value, so a caller can never
+	// release a lock it doesn't own (e.g. after its own TTL expired and a
+	// different caller acquired it in the meantime).
+	ReleaseLock(ctx context.Context, key, value string) error
+
+	Close()
+}
+
+// NewStoreFromEnv selects and constructs a Store based on the CACHE_BACKEND
+// environment variable (redis|memory|noop, defaults to redis).
+func NewStoreFromEnv() (Store, error) {
+	backend := Backend(os.Getenv("CACHE_BACKEND"))
+	if backend == "" {
+		backend = BackendRedis
+	}
+
+	switch backend {
+	case BackendRedis:
+		redisClient, err := NewRedisClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize Redis cache backend: %w", err)
+		}
+		return NewRedisStore(redisClient), nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	case BackendNoop:
+		return NewNoopStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown CACHE_BACKEND %q: must be redis, memory, or noop", backend)
+	}
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreGetSetProduct(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	if _, err := s.GetProduct(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetProduct on empty store: got err %v, want ErrNotFound", err)
+	}
+
+	if err := s.SetProduct(ctx, "p1", []byte(`{"id":"p1"}`), time.Minute); err != nil {
+		t.Fatalf("SetProduct: %v", err)
+	}
+	got, err := s.GetProduct(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetProduct after SetProduct: %v", err)
+	}
+	if string(got) != `{"id":"p1"}` {
+		t.Fatalf("GetProduct = %q, want %q", got, `{"id":"p1"}`)
+	}
+}
+
+func TestMemoryStoreGetProductsBatch(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := s.SetProduct(ctx, "p1", []byte(`{"id":"p1"}`), time.Minute); err != nil {
+		t.Fatalf("SetProduct: %v", err)
+	}
+	if err := s.SetProduct(ctx, "p3", []byte(`{"id":"p3"}`), time.Minute); err != nil {
+		t.Fatalf("SetProduct: %v", err)
+	}
+
+	got, err := s.GetProducts(ctx, []string{"p1", "p2", "p3"})
+	if err != nil {
+		t.Fatalf("GetProducts: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("GetProducts returned %d entries, want 3", len(got))
+	}
+	if string(got[0]) != `{"id":"p1"}` {
+		t.Fatalf("GetProducts[0] = %q, want %q", got[0], `{"id":"p1"}`)
+	}
+	if got[1] != nil {
+		t.Fatalf("GetProducts[1] = %q, want nil for uncached id", got[1])
+	}
+	if string(got[2]) != `{"id":"p3"}` {
+		t.Fatalf("GetProducts[2] = %q, want %q", got[2], `{"id":"p3"}`)
+	}
+}
+
+func TestMemoryStoreProductIDs(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := s.AddProductID(ctx, "p1"); err != nil {
+		t.Fatalf("AddProductID: %v", err)
+	}
+	if err := s.AddProductID(ctx, "p2"); err != nil {
+		t.Fatalf("AddProductID: %v", err)
+	}
+
+	ids, err := s.ListProductIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListProductIDs: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("ListProductIDs = %v, want 2 ids", ids)
+	}
+
+	if err := s.RemoveProductID(ctx, "p1"); err != nil {
+		t.Fatalf("RemoveProductID: %v", err)
+	}
+	ids, err = s.ListProductIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListProductIDs after remove: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "p2" {
+		t.Fatalf("ListProductIDs after remove = %v, want [p2]", ids)
+	}
+}
+
+func TestMemoryStorePipelineExec(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	if err := s.AddProductID(ctx, "stale"); err != nil {
+		t.Fatalf("AddProductID: %v", err)
+	}
+
+	p := s.Pipeline()
+	p.ClearProductIDs()
+	p.SetProduct("p1", []byte(`{"id":"p1"}`), time.Minute)
+	p.AddProductID("p1")
+	p.AddProductID("p2")
+	p.RemoveProductID("p2")
+	if err := p.Exec(ctx); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+
+	ids, err := s.ListProductIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListProductIDs: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != "p1" {
+		t.Fatalf("ListProductIDs after pipeline = %v, want [p1]", ids)
+	}
+
+	got, err := s.GetProduct(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetProduct: %v", err)
+	}
+	if string(got) != `{"id":"p1"}` {
+		t.Fatalf("GetProduct = %q, want %q", got, `{"id":"p1"}`)
+	}
+}
+
+func TestMemoryStoreLock(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+
+	acquired, err := s.AcquireLock(ctx, "k", "holder-a", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if !acquired {
+		t.Fatal("AcquireLock on free lock = false, want true")
+	}
+
+	acquired, err = s.AcquireLock(ctx, "k", "holder-b", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if acquired {
+		t.Fatal("AcquireLock on held lock = true, want false")
+	}
+
+	if err := s.ReleaseLock(ctx, "k", "holder-b"); err != nil {
+		t.Fatalf("ReleaseLock by non-owner: %v", err)
+	}
+	acquired, err = s.AcquireLock(ctx, "k", "holder-b", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if acquired {
+		t.Fatal("ReleaseLock by non-owner released the lock, want no-op")
+	}
+
+	if err := s.ReleaseLock(ctx, "k", "holder-a"); err != nil {
+		t.Fatalf("ReleaseLock by owner: %v", err)
+	}
+	acquired, err = s.AcquireLock(ctx, "k", "holder-b", time.Minute)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if !acquired {
+		t.Fatal("AcquireLock after owner release = false, want true")
+	}
+}
+
+func TestNoopStoreNeverCaches(t *testing.T) {
+	s := NewNoopStore()
+	ctx := context.Background()
+
+	if err := s.SetProduct(ctx, "p1", []byte(`{}`), time.Minute); err != nil {
+		t.Fatalf("SetProduct: %v", err)
+	}
+	if _, err := s.GetProduct(ctx, "p1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetProduct after SetProduct: got err %v, want ErrNotFound", err)
+	}
+
+	got, err := s.GetProducts(ctx, []string{"p1", "p2"})
+	if err != nil {
+		t.Fatalf("GetProducts: %v", err)
+	}
+	if len(got) != 2 || got[0] != nil || got[1] != nil {
+		t.Fatalf("GetProducts = %v, want [nil, nil]", got)
+	}
+
+	if err := s.AddProductID(ctx, "p1"); err != nil {
+		t.Fatalf("AddProductID: %v", err)
+	}
+	ids, err := s.ListProductIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListProductIDs: %v", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("ListProductIDs = %v, want empty", ids)
+	}
+
+	p := s.Pipeline()
+	p.SetProduct("p1", []byte(`{}`), time.Minute)
+	if err := p.Exec(ctx); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+}
+
+func TestNoopStoreLockAlwaysSucceeds(t *testing.T) {
+	s := NewNoopStore()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		acquired, err := s.AcquireLock(ctx, "k", "holder", time.Minute)
+		if err != nil {
+			t.Fatalf("AcquireLock: %v", err)
+		}
+		if !acquired {
+			t.Fatal("AcquireLock = false, want true (noop never coordinates)")
+		}
+	}
+}
+
+func TestNewStoreFromEnv(t *testing.T) {
+	t.Run("memory", func(t *testing.T) {
+		t.Setenv("CACHE_BACKEND", string(BackendMemory))
+		store, err := NewStoreFromEnv()
+		if err != nil {
+			t.Fatalf("NewStoreFromEnv: %v", err)
+		}
+		defer store.Close()
+		if _, ok := store.(*MemoryStore); !ok {
+			t.Fatalf("NewStoreFromEnv returned %T, want *MemoryStore", store)
+		}
+	})
+
+	t.Run("noop", func(t *testing.T) {
+		t.Setenv("CACHE_BACKEND", string(BackendNoop))
+		store, err := NewStoreFromEnv()
+		if err != nil {
+			t.Fatalf("NewStoreFromEnv: %v", err)
+		}
+		defer store.Close()
+		if _, ok := store.(*NoopStore); !ok {
+			t.Fatalf("NewStoreFromEnv returned %T, want *NoopStore", store)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		t.Setenv("CACHE_BACKEND", "bogus")
+		if _, err := NewStoreFromEnv(); err == nil {
+			t.Fatal("NewStoreFromEnv with unknown backend = nil error, want error")
+		}
+	})
+}
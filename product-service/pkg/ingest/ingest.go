@@ -0,0 +1,61 @@
+// Package ingest holds the CSV row type and per-row UPSERT logic shared by
+// the upload-csv producer (which validates and enqueues rows) and the
+// ingest-worker consumer (which applies them to PostgreSQL and the cache).
+package ingest
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Row is a single validated CSV row, ready to be queued for ingestion.
+type Row struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Image    string  `json:"image"`
+	Price    float64 `json:"price"`
+	Qty      int     `json:"qty"`
+	TenantID string  `json:"tenant_id,omitempty"`
+}
+
+// Job is the JSON payload pushed onto the ingest queue for a single row.
+// Attempt is incremented by the worker each time a row is requeued after a
+// failed UPSERT, and travels with the row since a retry is a brand new
+// queue message. Source identifies the row's origin (its tenant_id, or
+// falling back to the S3 bucket it was uploaded to) and is what the
+// ingest-worker's pkg/limiter keys rate limits and in-flight caps on.
+type Job struct {
+	Row     Row    `json:"row"`
+	Attempt int    `json:"attempt"`
+	Source  string `json:"source"`
+}
+
+// ParseRow validates and converts a raw CSV record (id, name, image, price,
+// qty, and an optional tenant_id) into a Row.
+func ParseRow(record []string) (Row, error) {
+	if len(record) < 5 {
+		return Row{}, fmt.Errorf("insufficient columns: got %d, want at least 5", len(record))
+	}
+
+	price, err := strconv.ParseFloat(record[3], 64)
+	if err != nil {
+		return Row{}, fmt.Errorf("invalid price %q: %w", record[3], err)
+	}
+
+	qty, err := strconv.Atoi(record[4])
+	if err != nil {
+		return Row{}, fmt.Errorf("invalid quantity %q: %w", record[4], err)
+	}
+
+	row := Row{
+		ID:    record[0],
+		Name:  record[1],
+		Image: record[2],
+		Price: price,
+		Qty:   qty,
+	}
+	if len(record) >= 6 {
+		row.TenantID = record[5]
+	}
+	return row, nil
+}
@@ -0,0 +1,84 @@
+package ingest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"gitlab.connectwisedev.com/product-service/models"
+	"gitlab.connectwisedev.com/product-service/pkg/cache"
+	"gitlab.connectwisedev.com/product-service/pkg/database"
+)
+
+// UpsertRow applies a single row to PostgreSQL inside its own short
+// transaction, then updates the cache to match. It's the per-row unit of
+// work run by the ingest-worker for each job it dequeues.
+func UpsertRow(ctx context.Context, db *database.DBClient, store cache.Store, row Row) (models.Product, error) {
+	tx, err := db.GetDB().BeginTx(ctx, nil)
+	if err != nil {
+		return models.Product{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback on error by default; no-op once committed.
+
+	productID := row.ID
+	if productID == "" {
+		productID = uuid.New().String()
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO products (id, name, image, price, qty)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE SET
+			image = EXCLUDED.image,
+			price = EXCLUDED.price,
+			qty = EXCLUDED.qty,
+			updated_at = NOW(),
+			out_of_stock = (EXCLUDED.qty = 0) -- Trigger handles this, but explicit here for clarity/robustness
+		RETURNING id;
+	`, productID, row.Name, nullString(row.Image), row.Price, row.Qty)
+	if err != nil {
+		return models.Product{}, fmt.Errorf("failed to UPSERT product %s: %w", row.Name, err)
+	}
+
+	// Re-fetch the product from DB to ensure we have the correct ID,
+	// created_at, updated_at, and out_of_stock status.
+	var product models.Product
+	var imageSQL sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT id, name, image, price, qty, out_of_stock, created_at, updated_at FROM products WHERE name = $1`, row.Name).
+		Scan(&product.ID, &product.Name, &imageSQL, &product.Price, &product.Qty, &product.OutOfStock, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		return models.Product{}, fmt.Errorf("failed to re-fetch product %s after UPSERT: %w", row.Name, err)
+	}
+	if imageSQL.Valid {
+		product.Image = &imageSQL.String
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Product{}, fmt.Errorf("failed to commit transaction for product %s: %w", row.Name, err)
+	}
+
+	productJSON, err := json.Marshal(product)
+	if err != nil {
+		return models.Product{}, fmt.Errorf("failed to marshal product %s for cache update: %w", product.Name, err)
+	}
+	if err := store.SetProduct(ctx, product.ID, productJSON, 0); err != nil { // No expiration
+		log.Printf("Error setting product %s in cache: %v", product.Name, err)
+	}
+	if err := store.AddProductID(ctx, product.ID); err != nil {
+		log.Printf("Error adding product ID %s to cache: %v", product.ID, err)
+	}
+
+	return product, nil
+}
+
+// nullString converts a Go string to sql.NullString for nullable DB columns.
+func nullString(s string) sql.NullString {
+	if s == "" {
+		return sql.NullString{Valid: false}
+	}
+	return sql.NullString{String: s, Valid: true}
+}
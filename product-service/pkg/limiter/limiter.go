@@ -0,0 +1,201 @@
+// Package limiter enforces per-source rate limits and concurrency caps on
+// the CSV ingest pipeline, so one noisy source (a CSV's tenant_id, falling
+// back to its S3 bucket) can't starve DB/Redis capacity for every other
+// source sharing the same ingest-worker fleet. Counters live in Redis so the
+// limits hold across every concurrently running Lambda invocation, not just
+// within one.
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"gitlab.connectwisedev.com/product-service/pkg/cache"
+)
+
+// defaultSource is used when a job has no tenant_id and no bucket could be
+// determined, so unattributed rows still share a single bucket of quota
+// rather than bypassing limiting entirely.
+const defaultSource = "default"
+
+// inFlightTTL bounds how long an in-flight slot can stay reserved, as a
+// safety net for the same crash/panic/freeze/recycle failure modes the
+// DLQ and retry system exists to handle: if the holder dies between
+// AcquireSlot and its deferred ReleaseSlot, the key expires on its own
+// instead of wedging the source at its cap forever. Sized to match the
+// longest a single row can run (an AWS Lambda invocation, at most 15
+// minutes, the outer bound on how long a slot can legitimately be held).
+const inFlightTTL = 15 * time.Minute
+
+// Config controls the rate and concurrency limits applied per source.
+// A zero value disables the corresponding check.
+type Config struct {
+	MaxRowsPerMin        int // INGEST_MAX_ROWS_PER_MIN: rows/minute allowed for any single source
+	MaxInFlightPerTenant int // INGEST_MAX_INFLIGHT_PER_TENANT: rows a single source may have processing concurrently
+}
+
+// ConfigFromEnv builds a Config from INGEST_MAX_ROWS_PER_MIN and
+// INGEST_MAX_INFLIGHT_PER_TENANT. Either may be left unset (or 0) to disable
+// that check, applying no limit as a global fallback.
+func ConfigFromEnv() (Config, error) {
+	var cfg Config
+
+	if v := os.Getenv("INGEST_MAX_ROWS_PER_MIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid INGEST_MAX_ROWS_PER_MIN value %q: %w", v, err)
+		}
+		cfg.MaxRowsPerMin = n
+	}
+	if v := os.Getenv("INGEST_MAX_INFLIGHT_PER_TENANT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid INGEST_MAX_INFLIGHT_PER_TENANT value %q: %w", v, err)
+		}
+		cfg.MaxInFlightPerTenant = n
+	}
+
+	return cfg, nil
+}
+
+// counterClient is the subset of redis.UniversalClient the limiter relies
+// on, narrowed the same way pkg/cache.Cmdable is so tests can exercise
+// Limiter's accounting logic against an in-process fake instead of a live
+// Redis.
+type counterClient interface {
+	Incr(ctx context.Context, key string) *redis.IntCmd
+	Decr(ctx context.Context, key string) *redis.IntCmd
+	Expire(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd
+}
+
+// Limiter enforces Config's limits against Redis-backed per-source counters.
+type Limiter struct {
+	client counterClient
+	cfg    Config
+}
+
+// New connects to Redis (using the same REDIS_MODE/REDIS_ADDR family of
+// settings as the product cache and ingest queue) and returns a Limiter
+// enforcing cfg.
+func New(cfg Config) (*Limiter, error) {
+	redisCfg, err := cache.ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Redis connection config for limiter: %w", err)
+	}
+
+	client, err := cache.NewUniversalClientFromConfig(redisCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis for limiter: %w", err)
+	}
+
+	return &Limiter{client: client, cfg: cfg}, nil
+}
+
+// Source picks the key a job's rate and concurrency limits are tracked
+// under: the row's tenant_id if set, else fallbackSource (typically the S3
+// bucket it was uploaded to), else defaultSource.
+func Source(tenantID, fallbackSource string) string {
+	if tenantID != "" {
+		return tenantID
+	}
+	if fallbackSource != "" {
+		return fallbackSource
+	}
+	return defaultSource
+}
+
+// AllowRow enforces a fixed one-minute window for source: it increments a
+// counter keyed to the current UTC minute and compares it against
+// MaxRowsPerMin. A MaxRowsPerMin of 0 allows every row.
+func (l *Limiter) AllowRow(ctx context.Context, source string) (bool, error) {
+	if l.cfg.MaxRowsPerMin <= 0 {
+		return true, nil
+	}
+
+	bucket := time.Now().UTC().Format("200601021504")
+	key := fmt.Sprintf("ingest:ratelimit:%s:%s", source, bucket)
+
+	count, err := l.client.Incr(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to increment rate limit counter for source %s: %w", source, err)
+	}
+	if count == 1 {
+		if err := l.client.Expire(ctx, key, 90*time.Second).Err(); err != nil {
+			log.Printf("Failed to set expiry on rate limit counter %s: %v", key, err)
+		}
+	}
+
+	allowed := count <= int64(l.cfg.MaxRowsPerMin)
+	if !allowed {
+		l.incrMetric(ctx, "limited_total", source)
+	}
+	return allowed, nil
+}
+
+// AcquireSlot reserves one of MaxInFlightPerTenant concurrent processing
+// slots for source, returning false if source is already at capacity. A
+// MaxInFlightPerTenant of 0 grants every request a slot.
+func (l *Limiter) AcquireSlot(ctx context.Context, source string) (bool, error) {
+	if l.cfg.MaxInFlightPerTenant <= 0 {
+		return true, nil
+	}
+
+	key := inFlightKey(source)
+	count, err := l.client.Incr(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to increment in-flight counter for source %s: %w", source, err)
+	}
+	// Refresh the TTL on every acquire (not just when count == 1) so the
+	// key keeps expiring inFlightTTL after the *last* acquire rather than
+	// the first, the same safety net AllowRow's rate-limit counter applies.
+	if err := l.client.Expire(ctx, key, inFlightTTL).Err(); err != nil {
+		log.Printf("Failed to set expiry on in-flight counter %s: %v", key, err)
+	}
+	if count > int64(l.cfg.MaxInFlightPerTenant) {
+		if err := l.client.Decr(ctx, key).Err(); err != nil {
+			log.Printf("Failed to decrement in-flight counter %s after rejecting slot: %v", key, err)
+		}
+		l.incrMetric(ctx, "deferred_total", source)
+		return false, nil
+	}
+	return true, nil
+}
+
+// ReleaseSlot frees a slot previously granted by AcquireSlot.
+func (l *Limiter) ReleaseSlot(ctx context.Context, source string) error {
+	if l.cfg.MaxInFlightPerTenant <= 0 {
+		return nil
+	}
+	if err := l.client.Decr(ctx, inFlightKey(source)).Err(); err != nil {
+		return fmt.Errorf("failed to decrement in-flight counter for source %s: %w", source, err)
+	}
+	return nil
+}
+
+func inFlightKey(source string) string {
+	return fmt.Sprintf("ingest:inflight:%s", source)
+}
+
+// incrMetric bumps a Redis counter for the named metric and logs it, since
+// this repo has no metrics client; ingest:metrics:* keys let an operator
+// inspect limited_total/deferred_total directly in Redis if needed.
+func (l *Limiter) incrMetric(ctx context.Context, name, source string) {
+	if err := l.client.Incr(ctx, fmt.Sprintf("ingest:metrics:%s", name)).Err(); err != nil {
+		log.Printf("Failed to increment %s metric: %v", name, err)
+	}
+	log.Printf("METRIC %s source=%s", name, source)
+}
+
+// Close releases the underlying Redis connection.
+func (l *Limiter) Close() error {
+	if closer, ok := l.client.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
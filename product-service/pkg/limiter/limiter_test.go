@@ -0,0 +1,163 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeCounterClient is an in-process counterClient backed by a map, so
+// AcquireSlot/ReleaseSlot accounting can be exercised without a live Redis.
+type fakeCounterClient struct {
+	mu       sync.Mutex
+	counters map[string]int64
+	ttls     map[string]time.Duration
+}
+
+func newFakeCounterClient() *fakeCounterClient {
+	return &fakeCounterClient{counters: make(map[string]int64), ttls: make(map[string]time.Duration)}
+}
+
+func (f *fakeCounterClient) Incr(ctx context.Context, key string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counters[key]++
+	cmd := redis.NewIntCmd(ctx)
+	cmd.SetVal(f.counters[key])
+	return cmd
+}
+
+func (f *fakeCounterClient) Decr(ctx context.Context, key string) *redis.IntCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.counters[key]--
+	cmd := redis.NewIntCmd(ctx)
+	cmd.SetVal(f.counters[key])
+	return cmd
+}
+
+func (f *fakeCounterClient) Expire(ctx context.Context, key string, ttl time.Duration) *redis.BoolCmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ttls[key] = ttl
+	cmd := redis.NewBoolCmd(ctx)
+	cmd.SetVal(true)
+	return cmd
+}
+
+func (f *fakeCounterClient) count(key string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.counters[key]
+}
+
+func (f *fakeCounterClient) ttl(key string) time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ttls[key]
+}
+
+func TestAcquireSlotRespectsCap(t *testing.T) {
+	client := newFakeCounterClient()
+	l := &Limiter{client: client, cfg: Config{MaxInFlightPerTenant: 2}}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		acquired, err := l.AcquireSlot(ctx, "tenant-a")
+		if err != nil {
+			t.Fatalf("AcquireSlot: %v", err)
+		}
+		if !acquired {
+			t.Fatalf("AcquireSlot #%d = false, want true (under cap)", i+1)
+		}
+	}
+
+	acquired, err := l.AcquireSlot(ctx, "tenant-a")
+	if err != nil {
+		t.Fatalf("AcquireSlot: %v", err)
+	}
+	if acquired {
+		t.Fatal("AcquireSlot over cap = true, want false")
+	}
+	// A rejected acquire must decrement back to the cap, not leave the
+	// counter incremented past it.
+	if got := client.count(inFlightKey("tenant-a")); got != 2 {
+		t.Fatalf("in-flight counter after rejected acquire = %d, want 2", got)
+	}
+
+	// A different source has its own independent counter.
+	acquired, err = l.AcquireSlot(ctx, "tenant-b")
+	if err != nil {
+		t.Fatalf("AcquireSlot: %v", err)
+	}
+	if !acquired {
+		t.Fatal("AcquireSlot for a different source = false, want true")
+	}
+}
+
+func TestAcquireSlotZeroCapAlwaysAllows(t *testing.T) {
+	client := newFakeCounterClient()
+	l := &Limiter{client: client, cfg: Config{MaxInFlightPerTenant: 0}}
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		acquired, err := l.AcquireSlot(ctx, "tenant-a")
+		if err != nil {
+			t.Fatalf("AcquireSlot: %v", err)
+		}
+		if !acquired {
+			t.Fatal("AcquireSlot with MaxInFlightPerTenant=0 = false, want true (no cap)")
+		}
+	}
+	// A disabled cap must never touch Redis, so no counter is created.
+	if got := client.count(inFlightKey("tenant-a")); got != 0 {
+		t.Fatalf("in-flight counter with cap disabled = %d, want 0 (untouched)", got)
+	}
+}
+
+func TestReleaseSlotFreesCapacity(t *testing.T) {
+	client := newFakeCounterClient()
+	l := &Limiter{client: client, cfg: Config{MaxInFlightPerTenant: 1}}
+	ctx := context.Background()
+
+	acquired, err := l.AcquireSlot(ctx, "tenant-a")
+	if err != nil || !acquired {
+		t.Fatalf("AcquireSlot: acquired=%v err=%v", acquired, err)
+	}
+
+	acquired, err = l.AcquireSlot(ctx, "tenant-a")
+	if err != nil {
+		t.Fatalf("AcquireSlot: %v", err)
+	}
+	if acquired {
+		t.Fatal("AcquireSlot at cap = true, want false")
+	}
+
+	if err := l.ReleaseSlot(ctx, "tenant-a"); err != nil {
+		t.Fatalf("ReleaseSlot: %v", err)
+	}
+
+	acquired, err = l.AcquireSlot(ctx, "tenant-a")
+	if err != nil {
+		t.Fatalf("AcquireSlot after release: %v", err)
+	}
+	if !acquired {
+		t.Fatal("AcquireSlot after ReleaseSlot = false, want true (slot freed)")
+	}
+}
+
+func TestAcquireSlotRefreshesTTL(t *testing.T) {
+	client := newFakeCounterClient()
+	l := &Limiter{client: client, cfg: Config{MaxInFlightPerTenant: 5}}
+	ctx := context.Background()
+
+	if _, err := l.AcquireSlot(ctx, "tenant-a"); err != nil {
+		t.Fatalf("AcquireSlot: %v", err)
+	}
+	if got := client.ttl(inFlightKey("tenant-a")); got != inFlightTTL {
+		t.Fatalf("in-flight counter TTL = %v, want %v (leak safety net)", got, inFlightTTL)
+	}
+}
@@ -0,0 +1,203 @@
+// Package queue provides the producer/consumer abstraction the CSV ingest
+// pipeline uses to hand rows from the S3-triggered Lambda to the
+// ingest-worker, so a single malformed row can be retried and eventually
+// dead-lettered without re-running the whole upload.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Job is a single unit of ingestion work: a CSV row's JSON payload, tagged
+// with an opaque backend message ID used to Ack, Retry, or DeadLetter it.
+// The attempt count travels inside Payload (see pkg/ingest.Job) rather than
+// here, since requeuing a row creates a new backend message with its own ID.
+type Job struct {
+	ID      string
+	Payload []byte
+}
+
+// Producer pushes ingestion jobs onto the durable queue.
+type Producer interface {
+	Push(ctx context.Context, payload []byte) error
+	// PushDelayed pushes payload so it isn't available for delivery until
+	// roughly delay has elapsed. Consumer.Defer uses it to defer jobs from a
+	// source that pkg/limiter reports over its limits instead of dropping them.
+	PushDelayed(ctx context.Context, payload []byte, delay time.Duration) error
+	Close() error
+}
+
+// Consumer reads ingestion jobs as part of a consumer group, acknowledging
+// successful processing and moving exhausted jobs to a dead-letter queue.
+type Consumer interface {
+	// Read returns up to batchSize jobs, blocking for up to blockTimeout
+	// when none are immediately available.
+	Read(ctx context.Context, batchSize int, blockTimeout time.Duration) ([]Job, error)
+	// Ack marks a job as successfully processed.
+	Ack(ctx context.Context, job Job) error
+	// Retry pushes retryPayload (the original row re-marshaled with an
+	// incremented attempt count) as a new job and acknowledges the current
+	// delivery, so it isn't redelivered alongside the retry.
+	Retry(ctx context.Context, job Job, retryPayload []byte) error
+	// DeadLetter moves an exhausted job to the dead-letter queue, recording
+	// the row, the error, and the attempt count, then acknowledges it.
+	DeadLetter(ctx context.Context, job Job, causeErr error, attempt int) error
+	// Defer re-enqueues payload (the job's original, unmodified payload)
+	// after delay and acknowledges the current delivery. Unlike Retry, it
+	// doesn't represent a failed attempt, so the ingest-worker uses it to back
+	// off a source that pkg/limiter reports over its rate limit or in-flight
+	// cap.
+	Defer(ctx context.Context, job Job, payload []byte, delay time.Duration) error
+	Close() error
+}
+
+// Backend selects which durable queue implementation backs the ingest
+// pipeline.
+type Backend string
+
+const (
+	// BackendRedisStreams uses Redis Streams (XADD/XREADGROUP/XACK).
+	BackendRedisStreams Backend = "redis"
+	// BackendSQS uses an SQS standard queue.
+	BackendSQS Backend = "sqs"
+)
+
+// Config controls queue topology and retry/backpressure behavior, read from
+// the environment by NewProducerFromEnv/NewConsumerFromEnv.
+type Config struct {
+	Backend           Backend
+	Stream            string // Redis stream name, or SQS queue URL
+	DLQStream         string // Redis DLQ stream name, or SQS DLQ queue URL
+	ConsumerGroup     string
+	ConsumerName      string
+	MaxAttempts       int
+	BatchSize         int
+	MaxInFlight       int
+	VisibilityTimeout time.Duration
+}
+
+const (
+	defaultStream         = "products:ingest"
+	defaultDLQStream      = "products:ingest:dlq"
+	defaultConsumerGroup  = "products-ingest-workers"
+	defaultMaxAttempts    = 5
+	defaultBatchSize      = 10
+	defaultMaxInFlight    = 10
+	defaultVisibilityTime = 30 * time.Second
+)
+
+// ConfigFromEnv builds a Config from QUEUE_BACKEND, PRODUCTS_INGEST_STREAM,
+// PRODUCTS_INGEST_DLQ_STREAM, INGEST_CONSUMER_GROUP, INGEST_CONSUMER_NAME,
+// INGEST_MAX_ATTEMPTS, INGEST_BATCH_SIZE, INGEST_MAX_INFLIGHT, and
+// INGEST_VISIBILITY_TIMEOUT_SECONDS.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Backend:           Backend(os.Getenv("QUEUE_BACKEND")),
+		Stream:            os.Getenv("PRODUCTS_INGEST_STREAM"),
+		DLQStream:         os.Getenv("PRODUCTS_INGEST_DLQ_STREAM"),
+		ConsumerGroup:     os.Getenv("INGEST_CONSUMER_GROUP"),
+		ConsumerName:      os.Getenv("INGEST_CONSUMER_NAME"),
+		MaxAttempts:       defaultMaxAttempts,
+		BatchSize:         defaultBatchSize,
+		MaxInFlight:       defaultMaxInFlight,
+		VisibilityTimeout: defaultVisibilityTime,
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = BackendRedisStreams
+	}
+	if cfg.Stream == "" {
+		cfg.Stream = defaultStream
+	}
+	if cfg.DLQStream == "" {
+		cfg.DLQStream = defaultDLQStream
+	}
+	if cfg.ConsumerGroup == "" {
+		cfg.ConsumerGroup = defaultConsumerGroup
+	}
+	if cfg.ConsumerName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "ingest-worker"
+		}
+		cfg.ConsumerName = hostname
+	}
+
+	if v := os.Getenv("INGEST_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid INGEST_MAX_ATTEMPTS value %q: %w", v, err)
+		}
+		cfg.MaxAttempts = n
+	}
+	if v := os.Getenv("INGEST_BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid INGEST_BATCH_SIZE value %q: %w", v, err)
+		}
+		cfg.BatchSize = n
+	}
+	if v := os.Getenv("INGEST_MAX_INFLIGHT"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid INGEST_MAX_INFLIGHT value %q: %w", v, err)
+		}
+		cfg.MaxInFlight = n
+	}
+	if v := os.Getenv("INGEST_VISIBILITY_TIMEOUT_SECONDS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid INGEST_VISIBILITY_TIMEOUT_SECONDS value %q: %w", v, err)
+		}
+		cfg.VisibilityTimeout = time.Duration(n) * time.Second
+	}
+
+	return cfg, nil
+}
+
+// NewProducerFromEnv selects and constructs a Producer based on Config read
+// from the environment.
+func NewProducerFromEnv() (Producer, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewProducer(cfg)
+}
+
+// NewConsumerFromEnv selects and constructs a Consumer based on Config read
+// from the environment.
+func NewConsumerFromEnv() (Consumer, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return NewConsumer(cfg)
+}
+
+// NewProducer constructs a Producer for cfg.Backend.
+func NewProducer(cfg Config) (Producer, error) {
+	switch cfg.Backend {
+	case BackendRedisStreams, "":
+		return NewRedisStreamQueue(cfg)
+	case BackendSQS:
+		return NewSQSQueue(cfg)
+	default:
+		return nil, fmt.Errorf("unknown QUEUE_BACKEND %q: must be redis or sqs", cfg.Backend)
+	}
+}
+
+// NewConsumer constructs a Consumer for cfg.Backend.
+func NewConsumer(cfg Config) (Consumer, error) {
+	switch cfg.Backend {
+	case BackendRedisStreams, "":
+		return NewRedisStreamQueue(cfg)
+	case BackendSQS:
+		return NewSQSQueue(cfg)
+	default:
+		return nil, fmt.Errorf("unknown QUEUE_BACKEND %q: must be redis or sqs", cfg.Backend)
+	}
+}
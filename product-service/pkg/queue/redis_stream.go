@@ -0,0 +1,234 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"gitlab.connectwisedev.com/product-service/pkg/cache"
+)
+
+// payloadField is the stream entry field holding the job's raw payload bytes.
+const payloadField = "payload"
+
+// RedisStreamQueue implements Producer and Consumer on top of a Redis
+// Stream, using a consumer group so multiple ingest-worker instances can
+// share one stream without processing the same entry twice.
+type RedisStreamQueue struct {
+	client redis.UniversalClient
+	cfg    Config
+}
+
+// NewRedisStreamQueue connects to Redis (using the same REDIS_MODE/REDIS_ADDR
+// family of settings as the product cache) and ensures the consumer group
+// exists on cfg.Stream.
+func NewRedisStreamQueue(cfg Config) (*RedisStreamQueue, error) {
+	redisCfg, err := cache.ConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Redis connection config for queue: %w", err)
+	}
+
+	client, err := cache.NewUniversalClientFromConfig(redisCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis for queue: %w", err)
+	}
+
+	q := &RedisStreamQueue{client: client, cfg: cfg}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.ensureGroup(ctx, cfg.Stream); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// ensureGroup creates the consumer group (and the stream, if it doesn't
+// exist yet) starting from the beginning of the stream.
+func (q *RedisStreamQueue) ensureGroup(ctx context.Context, stream string) error {
+	err := q.client.XGroupCreateMkStream(ctx, stream, q.cfg.ConsumerGroup, "0").Err()
+	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
+		return fmt.Errorf("failed to create consumer group %s on stream %s: %w", q.cfg.ConsumerGroup, stream, err)
+	}
+	return nil
+}
+
+// Push XADDs the payload onto the ingest stream.
+func (q *RedisStreamQueue) Push(ctx context.Context, payload []byte) error {
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.cfg.Stream,
+		Values: map[string]interface{}{payloadField: payload},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to XADD job to stream %s: %w", q.cfg.Stream, err)
+	}
+	return nil
+}
+
+// PushDelayed waits out delay and then XADDs the payload. Redis Streams has
+// no native delayed-delivery primitive, so the wait happens in-process;
+// callers should only pass delays short enough to fit inside one Lambda
+// invocation (the ingest-worker's deferDelay is sized accordingly).
+func (q *RedisStreamQueue) PushDelayed(ctx context.Context, payload []byte, delay time.Duration) error {
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return q.Push(ctx, payload)
+}
+
+// Read first reclaims any pending entries that have sat idle past
+// cfg.VisibilityTimeout (consumer crashed, panicked, or was recycled before
+// it could Ack/Retry/DeadLetter), then tops up the batch with new entries via
+// XREADGROUP, blocking for blockTimeout if the stream has nothing new.
+func (q *RedisStreamQueue) Read(ctx context.Context, batchSize int, blockTimeout time.Duration) ([]Job, error) {
+	jobs, err := q.claimStale(ctx, batchSize)
+	if err != nil {
+		log.Printf("Failed to reclaim stale pending entries on stream %s: %v", q.cfg.Stream, err)
+	}
+	if len(jobs) >= batchSize {
+		return jobs, nil
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.cfg.ConsumerGroup,
+		Consumer: q.cfg.ConsumerName,
+		Streams:  []string{q.cfg.Stream, ">"},
+		Count:    int64(batchSize) - int64(len(jobs)),
+		Block:    blockTimeout,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return jobs, nil
+		}
+		return jobs, fmt.Errorf("failed to XREADGROUP from stream %s: %w", q.cfg.Stream, err)
+	}
+
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			job, ok := q.jobFromMessage(msg)
+			if !ok {
+				continue
+			}
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// claimStale uses XAUTOCLAIM to take ownership of up to batchSize pending
+// entries that have been idle longer than cfg.VisibilityTimeout, so entries
+// abandoned by a dead consumer are eventually redelivered instead of sitting
+// in the consumer group's PEL forever.
+func (q *RedisStreamQueue) claimStale(ctx context.Context, batchSize int) ([]Job, error) {
+	messages, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.cfg.Stream,
+		Group:    q.cfg.ConsumerGroup,
+		Consumer: q.cfg.ConsumerName,
+		MinIdle:  q.cfg.VisibilityTimeout,
+		Start:    "0",
+		Count:    int64(batchSize),
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to XAUTOCLAIM from stream %s: %w", q.cfg.Stream, err)
+	}
+
+	var jobs []Job
+	for _, msg := range messages {
+		job, ok := q.jobFromMessage(msg)
+		if !ok {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// jobFromMessage decodes a stream message's payload field into a Job,
+// logging and skipping entries that are missing or malformed.
+func (q *RedisStreamQueue) jobFromMessage(msg redis.XMessage) (Job, bool) {
+	raw, ok := msg.Values[payloadField]
+	if !ok {
+		log.Printf("Stream entry %s missing %q field, skipping", msg.ID, payloadField)
+		return Job{}, false
+	}
+	payload, err := toBytes(raw)
+	if err != nil {
+		log.Printf("Stream entry %s has non-bytes payload, skipping: %v", msg.ID, err)
+		return Job{}, false
+	}
+	return Job{ID: msg.ID, Payload: payload}, true
+}
+
+func toBytes(v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case []byte:
+		return val, nil
+	case string:
+		return []byte(val), nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// Ack acknowledges successful processing of job, removing it from the
+// consumer group's pending entries list.
+func (q *RedisStreamQueue) Ack(ctx context.Context, job Job) error {
+	if err := q.client.XAck(ctx, q.cfg.Stream, q.cfg.ConsumerGroup, job.ID).Err(); err != nil {
+		return fmt.Errorf("failed to XACK job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// Retry pushes retryPayload as a new stream entry and acknowledges the
+// current delivery, so the row is redelivered as a fresh entry rather than
+// being redelivered in place.
+func (q *RedisStreamQueue) Retry(ctx context.Context, job Job, retryPayload []byte) error {
+	if err := q.Push(ctx, retryPayload); err != nil {
+		return fmt.Errorf("failed to re-enqueue job %s for retry: %w", job.ID, err)
+	}
+	return q.Ack(ctx, job)
+}
+
+// DeadLetter XADDs the original row, the error, and the attempt count onto
+// the DLQ stream, then acknowledges the original delivery.
+func (q *RedisStreamQueue) DeadLetter(ctx context.Context, job Job, causeErr error, attempt int) error {
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.cfg.DLQStream,
+		Values: map[string]interface{}{
+			payloadField: job.Payload,
+			"error":      causeErr.Error(),
+			"attempt":    attempt,
+		},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to XADD job %s to DLQ stream %s: %w", job.ID, q.cfg.DLQStream, err)
+	}
+	return q.Ack(ctx, job)
+}
+
+// Defer re-enqueues payload after delay and acknowledges the current
+// delivery, without treating it as a failed attempt.
+func (q *RedisStreamQueue) Defer(ctx context.Context, job Job, payload []byte, delay time.Duration) error {
+	if err := q.PushDelayed(ctx, payload, delay); err != nil {
+		return fmt.Errorf("failed to re-enqueue job %s for deferral: %w", job.ID, err)
+	}
+	return q.Ack(ctx, job)
+}
+
+// Close releases the underlying Redis connection.
+func (q *RedisStreamQueue) Close() error {
+	if closer, ok := q.client.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
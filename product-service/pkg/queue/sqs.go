@@ -0,0 +1,145 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// SQSQueue implements Producer and Consumer on top of two standard SQS
+// queues: cfg.Stream as the main ingest queue and cfg.DLQStream as its
+// dead-letter queue. It's the alternative to BackendRedisStreams for
+// operators who'd rather run ingestion on managed SQS.
+type SQSQueue struct {
+	client *sqs.Client
+	cfg    Config
+}
+
+// NewSQSQueue loads the default AWS config (the Lambda execution role's
+// credentials) and returns an SQSQueue targeting cfg.Stream/cfg.DLQStream as
+// queue URLs.
+func NewSQSQueue(cfg Config) (*SQSQueue, error) {
+	awsCfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config for SQS queue: %w", err)
+	}
+	return &SQSQueue{client: sqs.NewFromConfig(awsCfg), cfg: cfg}, nil
+}
+
+// Push sends the payload as an SQS message body to the ingest queue.
+func (q *SQSQueue) Push(ctx context.Context, payload []byte) error {
+	_, err := q.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.cfg.Stream),
+		MessageBody: aws.String(string(payload)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message to SQS queue %s: %w", q.cfg.Stream, err)
+	}
+	return nil
+}
+
+// PushDelayed sends the payload with SQS's native DelaySeconds, capped at
+// SQS's 900-second (15 minute) maximum.
+func (q *SQSQueue) PushDelayed(ctx context.Context, payload []byte, delay time.Duration) error {
+	delaySeconds := int32(delay / time.Second)
+	if delaySeconds > 900 {
+		delaySeconds = 900
+	}
+	_, err := q.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:     aws.String(q.cfg.Stream),
+		MessageBody:  aws.String(string(payload)),
+		DelaySeconds: delaySeconds,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send delayed message to SQS queue %s: %w", q.cfg.Stream, err)
+	}
+	return nil
+}
+
+// Read receives up to batchSize messages, waiting up to blockTimeout for at
+// least one to arrive (SQS long polling caps this at 20s).
+func (q *SQSQueue) Read(ctx context.Context, batchSize int, blockTimeout time.Duration) ([]Job, error) {
+	waitSeconds := int32(blockTimeout / time.Second)
+	if waitSeconds > 20 {
+		waitSeconds = 20
+	}
+
+	out, err := q.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(q.cfg.Stream),
+		MaxNumberOfMessages: int32(batchSize),
+		WaitTimeSeconds:     waitSeconds,
+		VisibilityTimeout:   int32(q.cfg.VisibilityTimeout / time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive messages from SQS queue %s: %w", q.cfg.Stream, err)
+	}
+
+	jobs := make([]Job, 0, len(out.Messages))
+	for _, msg := range out.Messages {
+		jobs = append(jobs, Job{ID: aws.ToString(msg.ReceiptHandle), Payload: []byte(aws.ToString(msg.Body))})
+	}
+	return jobs, nil
+}
+
+// Ack deletes the message from the ingest queue using its receipt handle.
+func (q *SQSQueue) Ack(ctx context.Context, job Job) error {
+	_, err := q.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.cfg.Stream),
+		ReceiptHandle: aws.String(job.ID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete SQS message: %w", err)
+	}
+	return nil
+}
+
+// Retry sends retryPayload as a new message and deletes the original.
+func (q *SQSQueue) Retry(ctx context.Context, job Job, retryPayload []byte) error {
+	if err := q.Push(ctx, retryPayload); err != nil {
+		return fmt.Errorf("failed to re-enqueue SQS message for retry: %w", err)
+	}
+	return q.Ack(ctx, job)
+}
+
+// DeadLetter sends the original row as the message body to the DLQ queue,
+// with the error and the attempt count as message attributes, then deletes
+// the original.
+func (q *SQSQueue) DeadLetter(ctx context.Context, job Job, causeErr error, attempt int) error {
+	_, err := q.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(q.cfg.DLQStream),
+		MessageBody: aws.String(string(job.Payload)),
+		MessageAttributes: map[string]types.MessageAttributeValue{
+			"error": {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(causeErr.Error()),
+			},
+			"attempt": {
+				DataType:    aws.String("Number"),
+				StringValue: aws.String(fmt.Sprintf("%d", attempt)),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message to SQS DLQ %s: %w", q.cfg.DLQStream, err)
+	}
+	return q.Ack(ctx, job)
+}
+
+// Defer sends payload back onto the ingest queue with the given delay and
+// deletes the original message, without treating it as a failed attempt.
+func (q *SQSQueue) Defer(ctx context.Context, job Job, payload []byte, delay time.Duration) error {
+	if err := q.PushDelayed(ctx, payload, delay); err != nil {
+		return fmt.Errorf("failed to re-enqueue SQS message for deferral: %w", err)
+	}
+	return q.Ack(ctx, job)
+}
+
+// Close is a no-op; the SQS SDK client holds no connection to release.
+func (q *SQSQueue) Close() error {
+	return nil
+}
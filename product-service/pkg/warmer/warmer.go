@@ -0,0 +1,124 @@
+// Package warmer refreshes the product cache from PostgreSQL, coordinating
+// across concurrent Lambda invocations so only one of them scans the DB and
+// rewrites the cache at a time.
+package warmer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"gitlab.connectwisedev.com/product-service/models"
+	"gitlab.connectwisedev.com/product-service/pkg/cache"
+	"gitlab.connectwisedev.com/product-service/pkg/database"
+)
+
+const (
+	// LockKey is the cache key used to coordinate warming across concurrent
+	// Lambda invocations.
+	LockKey = "products:cache:warming"
+	// LockTTL bounds how long a warming pass may hold the lock before it's
+	// considered abandoned and reclaimable by another invocation.
+	LockTTL = 60 * time.Second
+	// ProductTTL is the expiration set on individual product cache entries.
+	ProductTTL = 5 * time.Minute
+)
+
+// Warmer rescans PostgreSQL and rewrites the product ID set plus every
+// product:<id> key.
+type Warmer struct {
+	db         *database.DBClient
+	store      cache.Store
+	instanceID string
+}
+
+// New returns a Warmer identified by instanceID, the value stamped on the
+// lock so only the instance that acquired it can release it.
+func New(db *database.DBClient, store cache.Store, instanceID string) *Warmer {
+	return &Warmer{db: db, store: store, instanceID: instanceID}
+}
+
+// Refresh acquires the warming lock, rescans PostgreSQL, and rewrites the
+// cache. If the lock is already held by another instance it returns (0, nil)
+// without touching the cache.
+func (w *Warmer) Refresh(ctx context.Context) (int, error) {
+	acquired, err := w.store.AcquireLock(ctx, LockKey, w.instanceID, LockTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire cache warming lock: %w", err)
+	}
+	if !acquired {
+		log.Println("Cache warming lock already held by another instance, skipping.")
+		return 0, nil
+	}
+	defer func() {
+		if err := w.store.ReleaseLock(ctx, LockKey, w.instanceID); err != nil {
+			log.Printf("Failed to release cache warming lock: %v", err)
+		}
+	}()
+
+	products, err := w.fetchProductsFromDB(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan products from DB for cache warming: %w", err)
+	}
+
+	if err := w.writeToCache(ctx, products); err != nil {
+		return 0, err
+	}
+
+	log.Printf("Cache warmed with %d products.", len(products))
+	return len(products), nil
+}
+
+func (w *Warmer) fetchProductsFromDB(ctx context.Context) ([]models.Product, error) {
+	rows, err := w.db.GetDB().QueryContext(ctx, `SELECT id, name, image, price, qty, out_of_stock, created_at, updated_at FROM products ORDER BY name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products from DB: %w", err)
+	}
+	defer rows.Close()
+
+	var products []models.Product
+	for rows.Next() {
+		var p models.Product
+		var imageSQL sql.NullString // Use sql.NullString for nullable columns
+		if err := rows.Scan(&p.ID, &p.Name, &imageSQL, &p.Price, &p.Qty, &p.OutOfStock, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			log.Printf("Error scanning product row from DB: %v", err)
+			continue
+		}
+		if imageSQL.Valid {
+			p.Image = &imageSQL.String
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error during row iteration from DB: %w", err)
+	}
+	return products, nil
+}
+
+// writeToCache clears and then re-populates the entire product ID set and
+// individual product keys in a single pipeline.
+func (w *Warmer) writeToCache(ctx context.Context, products []models.Product) error {
+	pipe := w.store.Pipeline()
+
+	// Clear the existing product ID set first so it only ever reflects the
+	// IDs added by this warming pass; commands queue in pipeline order.
+	pipe.ClearProductIDs()
+
+	for _, p := range products {
+		productJSON, err := json.Marshal(p)
+		if err != nil {
+			log.Printf("Failed to marshal product %s for cache population: %v", p.ID, err)
+			continue
+		}
+		pipe.SetProduct(p.ID, productJSON, ProductTTL)
+		pipe.AddProductID(p.ID)
+	}
+
+	if err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to execute cache pipeline for cache population: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package warmer
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.connectwisedev.com/product-service/models"
+	"gitlab.connectwisedev.com/product-service/pkg/cache"
+)
+
+func TestRefreshSkipsWhenLockAlreadyHeld(t *testing.T) {
+	store := cache.NewMemoryStore()
+	ctx := context.Background()
+
+	acquired, err := store.AcquireLock(ctx, LockKey, "other-instance", LockTTL)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if !acquired {
+		t.Fatal("AcquireLock on free lock = false, want true")
+	}
+
+	// db is nil: Refresh must return before ever touching it, since another
+	// instance holds the warming lock.
+	w := New(nil, store, "this-instance")
+	n, err := w.Refresh(ctx)
+	if err != nil {
+		t.Fatalf("Refresh while lock held by another instance: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("Refresh while lock held = %d products, want 0", n)
+	}
+}
+
+func TestWriteToCacheClearsAndRepopulates(t *testing.T) {
+	store := cache.NewMemoryStore()
+	ctx := context.Background()
+
+	if err := store.AddProductID(ctx, "stale"); err != nil {
+		t.Fatalf("AddProductID: %v", err)
+	}
+
+	w := New(nil, store, "this-instance")
+	products := []models.Product{
+		{ID: "p1", Name: "Widget"},
+		{ID: "p2", Name: "Gadget"},
+	}
+	if err := w.writeToCache(ctx, products); err != nil {
+		t.Fatalf("writeToCache: %v", err)
+	}
+
+	ids, err := store.ListProductIDs(ctx)
+	if err != nil {
+		t.Fatalf("ListProductIDs: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("ListProductIDs after writeToCache = %v, want 2 ids (stale one cleared)", ids)
+	}
+
+	got, err := store.GetProduct(ctx, "p1")
+	if err != nil {
+		t.Fatalf("GetProduct(p1): %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatal("GetProduct(p1) returned empty JSON")
+	}
+}
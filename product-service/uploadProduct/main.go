@@ -1,44 +1,41 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/google/uuid"
 
-	"gitlab.connectwisedev.com/product-service/models"
-	"gitlab.connectwisedev.com/product-service/pkg/cache"
 	"gitlab.connectwisedev.com/product-service/pkg/config"
-	"gitlab.connectwisedev.com/product-service/pkg/database"
+	"gitlab.connectwisedev.com/product-service/pkg/ingest"
+	"gitlab.connectwisedev.com/product-service/pkg/limiter"
+	"gitlab.connectwisedev.com/product-service/pkg/queue"
 )
 
 var (
-	dbClient    *database.DBClient
-	redisClient *cache.RedisClient
-	ctx         = context.Background()
+	producer queue.Producer
+	ctx      = context.Background()
 )
 
 func init() {
 	config.LoadEnv() // Load environment variables first
 
 	var err error
-	dbClient, err = database.NewPostgresClient()
+	producer, err = queue.NewProducerFromEnv()
 	if err != nil {
-		log.Fatalf("Failed to initialize DB client: %v", err)
+		log.Fatalf("Failed to initialize ingest queue producer: %v", err)
 	}
 
-	redisClient, err = cache.NewRedisClient()
-	if err != nil {
-		log.Fatalf("Failed to initialize Redis client: %v", err)
+	if err := initS3Client(); err != nil {
+		log.Fatalf("Failed to initialize S3 client: %v", err)
 	}
 }
 
@@ -48,176 +45,136 @@ type S3EventWrapper struct {
 	CSVData string                 `json:"csv_data,omitempty"` // For local testing
 }
 
+// handler downloads each uploaded CSV from S3 (expanding manifests into
+// their referenced files), streams it row by row, and pushes each valid row
+// as an ingest.Job onto the ingest queue for the ingest-worker to apply. It
+// never buffers a whole object in memory, so multi-GB uploads don't OOM the
+// Lambda, and it doesn't touch PostgreSQL or the cache directly.
 func handler(event S3EventWrapper) error {
-	var csvContent []byte
-	var err error
+	if len(event.Records) == 0 && event.CSVData == "" {
+		return fmt.Errorf("no S3 event record or direct CSV data found in the payload")
+	}
 
-	if len(event.Records) > 0 {
-		// This path is for S3 event triggers (production).
-		// In a real scenario, you'd download the CSV from S3 here using AWS SDK for Go.
-		// For this assessment, if triggered by S3, we'll simulate by reading a local file IF in local env.
-		// Otherwise, it will fail, prompting you to implement real S3 download.
+	if event.CSVData != "" {
+		log.Println("Processing direct CSV data payload.")
+		return processCSVData(event.CSVData)
+	}
 
-		s3Record := event.Records[0].S3
-		bucketName := s3Record.Bucket.Name
-		key := s3Record.Object.Key
+	prefix := os.Getenv("PRODUCTS_S3_PREFIX")
 
-		log.Printf("Processing S3 event for bucket: %s, key: %s", bucketName, key)
+	var firstErr error
+	filesProcessed := 0
+	for _, record := range event.Records {
+		bucket := record.S3.Bucket.Name
+		key := record.S3.Object.Key
 
-		if os.Getenv("APP_ENV") == "local" {
-			log.Println("Running in local environment, attempting to read local CSV from 'products.csv' for S3 simulation.")
-			csvContent, err = os.ReadFile("products.csv") // Assume products.csv exists in the root for local testing
-			if err != nil {
-				return fmt.Errorf("failed to read local products.csv for S3 simulation: %w", err)
+		if prefix != "" && !strings.HasPrefix(key, prefix) {
+			log.Printf("Ignoring s3://%s/%s: outside configured PRODUCTS_S3_PREFIX %q", bucket, key, prefix)
+			continue
+		}
+
+		files, err := resolveFiles(ctx, bucket, key)
+		if err != nil {
+			log.Printf("Failed to resolve files for s3://%s/%s: %v", bucket, key, err)
+			if firstErr == nil {
+				firstErr = err
 			}
-		} else {
-			// **IMPORTANT:** For actual AWS deployment with S3 trigger,
-			// you must uncomment and implement AWS SDK S3 GetObject here.
-			// Example:
-			// sess, _ := session.NewSession()
-			// svc := s3.New(sess)
-			// result, err := svc.GetObject(&s3.GetObjectInput{
-			// 	Bucket: aws.String(bucketName),
-			// 	Key:    aws.String(key),
-			// })
-			// if err != nil {
-			// 	return fmt.Errorf("failed to get object from S3: %w", err)
-			// }
-			// defer result.Body.Close()
-			// csvContent, err = io.ReadAll(result.Body)
-			// if err != nil {
-			// 	return fmt.Errorf("failed to read S3 object body: %w", err)
-			// }
-			return fmt.Errorf("S3 event triggered, but S3 download logic is not implemented for non-local environment in this example. Please integrate AWS SDK for S3 if deploying to real AWS.")
+			continue
 		}
-	} else if event.CSVData != "" {
-		// This path is for direct invocation with CSV data (for local testing via Postman/CLI)
-		log.Println("Processing direct CSV data payload.")
-		csvContent = []byte(event.CSVData)
-	} else {
-		return fmt.Errorf("no S3 event record or direct CSV data found in the payload")
-	}
 
-	reader := csv.NewReader(bytes.NewReader(csvContent))
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to read CSV: %w", err)
+		for _, f := range files {
+			filesProcessed++
+			if err := processFile(f.Bucket, f.Key); err != nil {
+				log.Printf("Failed to process s3://%s/%s: %v", f.Bucket, f.Key, err)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
 	}
 
-	if len(records) < 2 {
-		return fmt.Errorf("CSV is empty or has only headers")
+	if filesProcessed == 0 && firstErr == nil {
+		return fmt.Errorf("no CSV files matched PRODUCTS_S3_PREFIX %q", prefix)
 	}
+	return firstErr
+}
 
-	// header := records[0] // If you need to validate headers explicitly
-	dataRows := records[1:]
+// processFile downloads and streams a single CSV object, logging row count,
+// byte count, and duration once it's done.
+func processFile(bucket, key string) error {
+	label := fmt.Sprintf("s3://%s/%s", bucket, key)
+	start := time.Now()
 
-	tx, err := dbClient.GetDB().BeginTx(ctx, nil)
+	body, byteCount, err := openObject(ctx, bucket, key)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return err
 	}
-	defer tx.Rollback() // Rollback on error by default
+	defer body.Close()
 
-	for i, row := range dataRows {
-		if len(row) < 5 { // Basic validation: check minimum number of columns (id, name, image, price, qty)
-			log.Printf("Skipping row %d due to insufficient columns: %v", i+2, row)
-			continue
-		}
-
-		productCSV := models.ProductCSV{}
-		// Map CSV columns to struct fields - adjust indices based on your CSV structure
-		// Assuming order: id, name, image, price, qty
-		productCSV.ID = row[0] // If CSV provides ID
-		productCSV.Name = row[1]
-		productCSV.Image = row[2] // This can be empty string for NULL
+	enqueued, total, err := streamCSV(body, bucket)
+	log.Printf("Ingest complete for %s: rows=%d enqueued=%d bytes=%d duration=%s", label, total, enqueued, byteCount, time.Since(start))
+	return err
+}
 
-		productCSV.Price, err = strconv.ParseFloat(row[3], 64)
-		if err != nil {
-			log.Printf("Skipping row %d: Invalid price '%s': %v", i+2, row[3], err)
-			continue
-		}
+// processCSVData handles the local-testing path where the CSV is passed
+// directly in the invocation payload instead of via S3.
+func processCSVData(data string) error {
+	start := time.Now()
+	enqueued, total, err := streamCSV(strings.NewReader(data), "direct-payload")
+	log.Printf("Ingest complete for direct-payload: rows=%d enqueued=%d bytes=%d duration=%s", total, enqueued, len(data), time.Since(start))
+	return err
+}
 
-		productCSV.Qty, err = strconv.Atoi(row[4])
-		if err != nil {
-			log.Printf("Skipping row %d: Invalid quantity '%s': %v", i+2, row[4], err)
-			continue
+// streamCSV reads r row by row via csv.Reader (never buffering the whole
+// object), validating and enqueuing each row. source identifies the file's
+// origin (typically its S3 bucket) and is used as the rate-limiting source
+// for any row that doesn't carry its own tenant_id. It returns how many rows
+// were enqueued and how many data rows were seen in total.
+func streamCSV(r io.Reader, source string) (enqueued int, total int, err error) {
+	reader := csv.NewReader(r)
+
+	if _, err := reader.Read(); err != nil { // header
+		if err == io.EOF {
+			return 0, 0, fmt.Errorf("CSV is empty")
 		}
+		return 0, 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
 
-		// Handle ID: If CSV provides ID, use it. Otherwise, generate a new one.
-		productID := productCSV.ID
-		if productID == "" {
-			productID = uuid.New().String()
+	for {
+		record, readErr := reader.Read()
+		if readErr == io.EOF {
+			break
 		}
-
-		// UPSERT into PostgreSQL
-		_, err = tx.ExecContext(ctx, `
-			INSERT INTO products (id, name, image, price, qty)
-			VALUES ($1, $2, $3, $4, $5)
-			ON CONFLICT (name) DO UPDATE SET
-				image = EXCLUDED.image,
-				price = EXCLUDED.price,
-				qty = EXCLUDED.qty,
-				updated_at = NOW(),
-				out_of_stock = (EXCLUDED.qty = 0) -- Trigger handles this, but explicit here for clarity/robustness
-			RETURNING id;
-		`, productID, productCSV.Name, nullString(productCSV.Image), productCSV.Price, productCSV.Qty)
-		if err != nil {
-			log.Printf("Error processing product %s (row %d) for DB UPSERT: %v", productCSV.Name, i+2, err)
-			continue // Continue processing other rows even if one fails
+		if readErr != nil {
+			return enqueued, total, fmt.Errorf("failed to read CSV row %d: %w", total+2, readErr)
 		}
+		total++
 
-		// Re-fetch the product from DB to ensure we have the correct ID, created_at, updated_at, out_of_stock status
-		// This is important because ID might be generated or fetched by `RETURNING id`.
-		var storedProduct models.Product
-		var imageSQL sql.NullString
-		rowDB := tx.QueryRowContext(ctx, `SELECT id, name, image, price, qty, out_of_stock, created_at, updated_at FROM products WHERE name = $1`, productCSV.Name)
-		err = rowDB.Scan(&storedProduct.ID, &storedProduct.Name, &imageSQL, &storedProduct.Price, &storedProduct.Qty, &storedProduct.OutOfStock, &storedProduct.CreatedAt, &storedProduct.UpdatedAt)
+		row, err := ingest.ParseRow(record)
 		if err != nil {
-			log.Printf("Error re-fetching product %s for cache update: %v", productCSV.Name, err)
+			log.Printf("Skipping row %d: %v", total+1, err)
 			continue
 		}
-		if imageSQL.Valid {
-			storedProduct.Image = &imageSQL.String
-		}
 
-		productJSON, err := json.Marshal(storedProduct)
-		if err != nil {
-			log.Printf("Error marshaling product %s to JSON for Redis: %v", storedProduct.Name, err)
+		if err := enqueueRow(row, source); err != nil {
+			log.Printf("Failed to enqueue row %d (product %s): %v", total+1, row.Name, err)
 			continue
 		}
-
-		// Update Redis cache for this product (individual key)
-		err = redisClient.GetClient().Set(ctx, fmt.Sprintf("product:%s", storedProduct.ID), productJSON, 0).Err() // No expiration
-		if err != nil {
-			log.Printf("Error setting product %s in Redis: %v", storedProduct.Name, err)
-			// This is a soft failure for Redis, continue processing DB
-		}
-
-		// Add product ID to a set for easy retrieval of all product IDs in getAllProducts
-		err = redisClient.GetClient().SAdd(ctx, "all_product_ids", storedProduct.ID).Err()
-		if err != nil {
-			log.Printf("Error adding product ID %s to all_product_ids set in Redis: %v", storedProduct.ID, err)
-		}
+		enqueued++
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	log.Println("Products processed successfully and cache updated.")
-	return nil
+	return enqueued, total, nil
 }
 
-// nullString converts a Go string to sql.NullString for nullable DB columns
-func nullString(s string) sql.NullString {
-	if s == "" {
-		return sql.NullString{Valid: false}
+func enqueueRow(row ingest.Row, source string) error {
+	payload, err := json.Marshal(ingest.Job{Row: row, Attempt: 1, Source: limiter.Source(row.TenantID, source)})
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
 	}
-	return sql.NullString{String: s, Valid: true}
+	return producer.Push(ctx, payload)
 }
 
 func main() {
-	defer dbClient.Close()
-	defer redisClient.Close()
+	defer producer.Close()
 	lambda.Start(handler)
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3File identifies a single CSV object to ingest.
+type s3File struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+}
+
+// manifest is the shape of a `*.manifest.json` object: a list of CSV files
+// to ingest in one invocation.
+type manifest struct {
+	Files []s3File `json:"files"`
+}
+
+var s3Client *s3.Client
+
+func initS3Client() error {
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config for S3 client: %w", err)
+	}
+	s3Client = s3.NewFromConfig(awsCfg)
+	return nil
+}
+
+// resolveFiles expands a single S3 event record into the list of CSV files
+// it refers to: just itself, unless the key is a manifest, in which case the
+// manifest's files are returned instead.
+func resolveFiles(ctx context.Context, bucket, key string) ([]s3File, error) {
+	if !strings.HasSuffix(key, ".manifest.json") {
+		return []s3File{{Bucket: bucket, Key: key}}, nil
+	}
+
+	body, _, err := openObject(ctx, bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest %s/%s: %w", bucket, key, err)
+	}
+	defer body.Close()
+
+	var m manifest
+	if err := json.NewDecoder(body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s/%s: %w", bucket, key, err)
+	}
+
+	log.Printf("Manifest %s/%s references %d file(s).", bucket, key, len(m.Files))
+	return m.Files, nil
+}
+
+// openObject returns a stream for bucket/key, transparently gunzipping it
+// when the key ends in .gz or the object was stored with a gzip
+// Content-Encoding. The returned byteCount is the object's size on S3 as
+// reported by the GetObject response (the compressed size, if gzip'd).
+func openObject(ctx context.Context, bucket, key string) (io.ReadCloser, int64, error) {
+	if os.Getenv("APP_ENV") == "local" {
+		return openLocalObject(bucket, key)
+	}
+
+	out, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get object s3://%s/%s: %w", bucket, key, err)
+	}
+
+	byteCount := aws.ToInt64(out.ContentLength)
+
+	isGzip := strings.HasSuffix(key, ".gz") || aws.ToString(out.ContentEncoding) == "gzip"
+	if !isGzip {
+		return out.Body, byteCount, nil
+	}
+
+	gz, err := gzip.NewReader(out.Body)
+	if err != nil {
+		out.Body.Close()
+		return nil, 0, fmt.Errorf("failed to open gzip stream for s3://%s/%s: %w", bucket, key, err)
+	}
+	return gzipReadCloser{gz: gz, body: out.Body}, byteCount, nil
+}
+
+// openLocalObject simulates an S3 GetObject by reading a local file, for
+// APP_ENV=local development and testing without a real bucket.
+func openLocalObject(bucket, key string) (io.ReadCloser, int64, error) {
+	path := key
+	if path == "" {
+		path = "products.csv"
+	}
+	log.Printf("Running in local environment, reading local file %q for S3 simulation (bucket %q).", path, bucket)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read local file %q for S3 simulation: %w", path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("failed to stat local file %q: %w", path, err)
+	}
+
+	if !strings.HasSuffix(path, ".gz") {
+		return f, info.Size(), nil
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("failed to open gzip stream for local file %q: %w", path, err)
+	}
+	return gzipReadCloser{gz: gz, body: f}, info.Size(), nil
+}
+
+// gzipReadCloser closes both the gzip reader and the underlying body.
+type gzipReadCloser struct {
+	gz   *gzip.Reader
+	body io.Closer
+}
+
+func (g gzipReadCloser) Read(p []byte) (int, error) {
+	return g.gz.Read(p)
+}
+
+func (g gzipReadCloser) Close() error {
+	if err := g.gz.Close(); err != nil {
+		g.body.Close()
+		return err
+	}
+	return g.body.Close()
+}